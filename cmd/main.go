@@ -3,25 +3,47 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	core "doihive/core"
+
+	// 纯 Go 实现的 sqlite3 驱动，不需要 cgo；只负责向 database/sql 注册自己，
+	// 真正的查询逻辑在 core.extractZoteroRecords 里，这里只是让 .sqlite（Zotero 库）
+	// 输入格式在默认构建下就能用，而不需要使用者自己额外 blank-import 一个驱动。
+	_ "modernc.org/sqlite"
 )
 
 func main() {
 	// 定义命令行参数（支持 -a 和 --archive）
 	var (
-		archiveDirShort = flag.String("a", "", "Archive directory path containing WoS TXT files (required)")
-		archiveDirLong  = flag.String("archive", "", "Archive directory path containing WoS TXT files (required)")
+		archiveDirShort = flag.String("a", "", "Archive directory path containing bibliography files (required)")
+		archiveDirLong  = flag.String("archive", "", "Archive directory path containing bibliography files (required)")
 		budgetShort     = flag.Int("b", 0, "Limit number of DOIs to download (0 = all, default: 0)")
 		budgetLong      = flag.Int("budget", 0, "Limit number of DOIs to download (0 = all, default: 0)")
 		workersShort    = flag.Int("w", 0, "Number of concurrent workers (default: 16)")
 		workersLong     = flag.Int("workers", 0, "Number of concurrent workers (default: 16)")
 		pdfDir          = flag.String("pdf", "./pdf", "PDF output directory (default: ./pdf)")
+		mirrors         = flag.String("mirrors", "", "Comma-separated list of Sci-Hub mirror base URLs (default: https://sci-hub.se)")
+		mirrorsFile     = flag.String("mirrors-file", "", "Path to a plain-text file listing one mirror base URL per line")
+		verify          = flag.Bool("verify", false, "Re-hash every PDF in the manifest and report tampered/truncated files, then exit")
+		resolversFlag   = flag.String("resolvers", "sci-hub", "Comma-separated resolver chain, tried in order: sci-hub, unpaywall, crossref, openaccessbutton")
+		unpaywallEmail  = flag.String("unpaywall-email", os.Getenv("DOIHIVE_UNPAYWALL_EMAIL"), "Contact email required by the Unpaywall API (env DOIHIVE_UNPAYWALL_EMAIL)")
+		doiColumn       = flag.String("doi-column", "doi", "Column name holding the DOI when reading generic CSV files")
+		probeMirrors    = flag.Bool("probe-mirrors", true, "Probe all mirrors with a lightweight HEAD request before downloading (default: true)")
+		captchaEndpoint = flag.String("captcha-endpoint", "", "External HTTP endpoint that solves Sci-Hub captcha images (empty = give up on captcha pages)")
+		proxies         = flag.String("proxies", "", "Comma-separated list of http/https/socks5 proxy URLs to rotate egress IPs through")
+		proxiesFile     = flag.String("proxies-file", "", "Path to a plain-text file listing one proxy URL per line")
+		allowDirect     = flag.Bool("allow-direct", true, "Fall back to a direct connection when every proxy is quarantined (default: true)")
+		proxyRetestURL  = flag.String("proxy-retest-url", "https://sci-hub.se", "URL used to periodically re-test quarantined proxies")
+		events          = flag.Bool("events", false, "Print JobQueued/JobStarted/JobSucceeded/JobFailed/MirrorQuarantined events to stderr as they happen")
+		rotatingLog     = flag.Bool("rotating-log", false, "Append each result to a rotating JSONL shard under <pdf>/logs as it completes, for crash-resilient stats reconstruction")
+		reconstructLog  = flag.String("reconstruct-log", "", "Timestamp (as used in the log shard filenames) to reconstruct download stats from <pdf>/logs JSONL shards, then exit")
 		help            = flag.Bool("help", false, "Show help message")
 	)
 
@@ -34,6 +56,12 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  %s -a ./archive\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -a ./archive -b 10 -w 8\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s --archive ./archive --budget 100 --workers 4\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -a ./archive --mirrors https://sci-hub.se,https://sci-hub.ru\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --verify --pdf ./pdf\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -a ./archive --doi-column doi\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -a ./archive --events\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -a ./archive --rotating-log\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --reconstruct-log 2026-07-29_10-00-00 --pdf ./pdf\n", os.Args[0])
 	}
 
 	flag.Parse()
@@ -44,6 +72,18 @@ func main() {
 		os.Exit(0)
 	}
 
+	// --verify 模式：只校验清单中已记录的 PDF 是否完好，不扫描 archive
+	if *verify {
+		runVerify(*pdfDir)
+		return
+	}
+
+	// --reconstruct-log 模式：从某次运行留下的 JSONL 分片重建下载统计，不重新下载
+	if *reconstructLog != "" {
+		runReconstructLog(*pdfDir, *reconstructLog)
+		return
+	}
+
 	// 检查必需的参数（支持 -a 或 --archive）
 	var archiveDir string
 	if *archiveDirShort != "" {
@@ -73,9 +113,11 @@ func main() {
 
 	fmt.Printf("📂 Archive 目录: %s\n\n", absPath)
 
+	parseOpts := core.ParseOptions{DOIColumn: *doiColumn}
+
 	// 1. 检查 DOI 记录
 	fmt.Println("🔍 开始检查 DOI 记录...")
-	checkResult, err := core.CheckDOIs(absPath)
+	checkResult, err := core.CheckDOIsWithOptions(absPath, parseOpts)
 	if err != nil {
 		fmt.Printf("❌ 错误: %v\n", err)
 		os.Exit(1)
@@ -86,7 +128,7 @@ func main() {
 
 	// 2. 提取所有有效的 DOI
 	fmt.Println("\n🔍 提取所有有效的 DOI...")
-	dois, err := core.ExtractDOIs(absPath)
+	dois, err := core.ExtractDOIsWithOptions(absPath, parseOpts)
 	if err != nil {
 		fmt.Printf("❌ 错误: %v\n", err)
 		os.Exit(1)
@@ -94,15 +136,51 @@ func main() {
 
 	fmt.Printf("✅ 发现 %d 个有效 DOI\n", len(dois))
 
-	// 3. 构建 URL
-	sciHubURL := "https://sci-hub.se"
-	urls := make([]string, 0, len(dois))
-	for _, doi := range dois {
-		url := fmt.Sprintf("%s/%s", sciHubURL, doi)
-		urls = append(urls, url)
+	// 3. 构建镜像池
+	mirrorList, err := resolveMirrors(*mirrors, *mirrorsFile)
+	if err != nil {
+		fmt.Printf("❌ 错误: %v\n", err)
+		os.Exit(1)
+	}
+
+	pool, err := core.NewMirrorPool(mirrorList)
+	if err != nil {
+		fmt.Printf("❌ 错误: 无法初始化镜像池: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✅ 已加载 %d 个镜像: %s\n", len(mirrorList), strings.Join(mirrorList, ", "))
+
+	if *probeMirrors {
+		fmt.Println("🩺 正在探测镜像健康状况...")
+		pool.ProbeMirrors(nil, 5*time.Second)
+	}
+
+	// 构建代理池（可选）：未指定 --proxies/--proxies-file 时为空池，全部走直连
+	proxyList, err := resolveProxies(*proxies, *proxiesFile)
+	if err != nil {
+		fmt.Printf("❌ 错误: %v\n", err)
+		os.Exit(1)
+	}
+
+	proxyPool, err := core.NewProxyPool(proxyList, *allowDirect)
+	if err != nil {
+		fmt.Printf("❌ 错误: 无法初始化代理池: %v\n", err)
+		os.Exit(1)
 	}
 
-	fmt.Printf("✅ 构建了 %d 个 URL\n", len(urls))
+	if len(proxyList) > 0 {
+		fmt.Printf("✅ 已加载 %d 个代理，出口 IP 将按健康分数轮换\n", len(proxyList))
+		go proxyPool.RetestQuarantined(context.Background(), *proxyRetestURL, 5*time.Minute)
+	}
+
+	// 构建解析器链
+	resolvers, err := buildResolverChain(*resolversFlag, pool, *unpaywallEmail)
+	if err != nil {
+		fmt.Printf("❌ 错误: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ 解析器链: %s\n", *resolversFlag)
 
 	// 根据 budget 参数限制数量
 	var budget int
@@ -112,9 +190,9 @@ func main() {
 		budget = *budgetLong
 	}
 
-	if budget > 0 && budget < len(urls) {
-		fmt.Printf("⚠️  限制为前 %d 个 URL\n", budget)
-		urls = urls[:budget]
+	if budget > 0 && budget < len(dois) {
+		fmt.Printf("⚠️  限制为前 %d 个 DOI\n", budget)
+		dois = dois[:budget]
 	}
 
 	// 确定并发数
@@ -136,7 +214,74 @@ func main() {
 		os.Exit(1)
 	}
 
-	stats, err := core.DownloadPDFs(urls, absPdfDir, workers)
+	manifest, err := core.NewManifest(absPdfDir)
+	if err != nil {
+		fmt.Printf("❌ 错误: 无法加载下载清单: %v\n", err)
+		os.Exit(1)
+	}
+
+	history, err := core.NewHistory(absPdfDir)
+	if err != nil {
+		fmt.Printf("❌ 错误: 无法加载历史记录: %v\n", err)
+		os.Exit(1)
+	}
+
+	var captchaSolver core.CaptchaSolver
+	if *captchaEndpoint != "" {
+		captchaSolver = core.NewHTTPCaptchaSolver(*captchaEndpoint)
+	}
+
+	// 启用 --events 时订阅 EventBus，把 JobQueued/JobStarted/JobSucceeded/JobFailed/
+	// MirrorQuarantined 打印到标准错误——这是最简单的消费者，真正的 TUI 进度条/
+	// Prometheus exporter/SSE 端点可以用同样的方式订阅同一个 bus
+	var bus *core.EventBus
+	if *events {
+		bus = core.NewEventBus()
+		ch := bus.Subscribe(256)
+		go func() {
+			for evt := range ch {
+				switch e := evt.(type) {
+				case core.JobQueued:
+					fmt.Fprintf(os.Stderr, "📝 [event] 已入队: %s\n", e.DOI)
+				case core.JobStarted:
+					fmt.Fprintf(os.Stderr, "🚀 [event] 开始下载: %s\n", e.DOI)
+				case core.JobSucceeded:
+					fmt.Fprintf(os.Stderr, "✅ [event] 下载成功: %s (%s, %s)\n", e.DOI, formatSize(e.Bytes), formatDuration(e.Elapsed))
+				case core.JobFailed:
+					fmt.Fprintf(os.Stderr, "❌ [event] 下载失败: %s (%s): %s\n", e.DOI, e.Mirror, e.Err)
+				case core.MirrorQuarantined:
+					fmt.Fprintf(os.Stderr, "🧊 [event] 镜像被隔离: %s\n", e.Mirror)
+				}
+			}
+		}()
+		defer bus.Close()
+	}
+
+	// 启用 --rotating-log 时，每个终态结果都会被立即追加写入 <pdf>/logs 下的 JSONL 分片，
+	// 进程中途被杀死也能事后用 --reconstruct-log 恢复出完整的统计
+	var rotLogger *core.RotatingLogger
+	if *rotatingLog {
+		rotLogger, err = core.NewRotatingLogger(absPdfDir)
+		if err != nil {
+			fmt.Printf("❌ 错误: 无法初始化滚动日志: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ 滚动日志已启用，写入 %s (时间戳 %s)\n", rotLogger.LogDir, rotLogger.Timestamp)
+		defer rotLogger.Close()
+	}
+
+	stats, err := core.DownloadPDFs(dois, core.PipelineOptions{
+		PDFDir:        absPdfDir,
+		MaxWorkers:    workers,
+		Pool:          pool,
+		Manifest:      manifest,
+		History:       history,
+		Proxies:       proxyPool,
+		Resolvers:     resolvers,
+		CaptchaSolver: captchaSolver,
+		RotLogger:     rotLogger,
+		Bus:           bus,
+	})
 	if err != nil {
 		fmt.Printf("❌ 错误: %v\n", err)
 		os.Exit(1)
@@ -146,6 +291,136 @@ func main() {
 	printDownloadStats(stats)
 }
 
+// runVerify 实现 --verify 模式：重新计算清单中每个已成功 PDF 的 SHA-256，
+// 并报告被篡改或截断的文件。
+func runVerify(pdfDir string) {
+	absPdfDir, err := filepath.Abs(pdfDir)
+	if err != nil {
+		fmt.Printf("❌ 错误: 无法解析 PDF 目录路径: %v\n", err)
+		os.Exit(1)
+	}
+
+	manifest, err := core.NewManifest(absPdfDir)
+	if err != nil {
+		fmt.Printf("❌ 错误: 无法加载下载清单: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("🔍 正在校验清单: %s\n", absPdfDir)
+	results, err := manifest.VerifyAll(absPdfDir)
+	if err != nil {
+		fmt.Printf("❌ 错误: %v\n", err)
+		os.Exit(1)
+	}
+
+	var ok, bad int
+	for _, r := range results {
+		if r.OK {
+			ok++
+			continue
+		}
+		bad++
+		fmt.Printf("❌ %s: %s\n", r.DOI, r.Reason)
+	}
+
+	fmt.Printf("\n📊 校验完成: %d 个完好, %d 个损坏 (共 %d 条清单记录)\n", ok, bad, len(results))
+}
+
+// runReconstructLog 实现 --reconstruct-log 模式：从一次用 --rotating-log 跑过的下载
+// 留下的 JSONL 分片重建出 DownloadStats（可重建的字段见 core.ReconstructStatsFromShards），
+// 即使那次进程中途被杀死也不例外。
+func runReconstructLog(pdfDir, timestamp string) {
+	absPdfDir, err := filepath.Abs(pdfDir)
+	if err != nil {
+		fmt.Printf("❌ 错误: 无法解析 PDF 目录路径: %v\n", err)
+		os.Exit(1)
+	}
+
+	logDir := filepath.Join(absPdfDir, "logs")
+	fmt.Printf("🔍 正在从 %s 下时间戳为 %s 的日志分片重建统计...\n", logDir, timestamp)
+
+	stats, err := core.ReconstructStatsFromShards(logDir, timestamp)
+	if err != nil {
+		fmt.Printf("❌ 错误: %v\n", err)
+		os.Exit(1)
+	}
+
+	printDownloadStats(stats)
+}
+
+// resolveMirrors 决定最终使用的镜像列表：--mirrors 优先于 --mirrors-file，
+// 两者都未指定时回退到默认的 sci-hub.se。
+func resolveMirrors(mirrorsFlag, mirrorsFilePath string) ([]string, error) {
+	if mirrorsFlag != "" {
+		parts := strings.Split(mirrorsFlag, ",")
+		mirrors := make([]string, 0, len(parts))
+		for _, p := range parts {
+			p = strings.TrimSpace(p)
+			if p != "" {
+				mirrors = append(mirrors, p)
+			}
+		}
+		return mirrors, nil
+	}
+
+	if mirrorsFilePath != "" {
+		return core.LoadMirrorsFromFile(mirrorsFilePath)
+	}
+
+	return []string{"https://sci-hub.se"}, nil
+}
+
+// resolveProxies 解析 --proxies/--proxies-file，二者都未指定时返回空列表（代理池退化为直连）
+func resolveProxies(proxiesFlag, proxiesFilePath string) ([]string, error) {
+	if proxiesFlag != "" {
+		parts := strings.Split(proxiesFlag, ",")
+		proxies := make([]string, 0, len(parts))
+		for _, p := range parts {
+			p = strings.TrimSpace(p)
+			if p != "" {
+				proxies = append(proxies, p)
+			}
+		}
+		return proxies, nil
+	}
+
+	if proxiesFilePath != "" {
+		return core.LoadProxiesFromFile(proxiesFilePath)
+	}
+
+	return []string{}, nil
+}
+
+// buildResolverChain 根据 --resolvers 指定的名称列表，按顺序构建解析器链
+func buildResolverChain(spec string, pool *core.MirrorPool, unpaywallEmail string) ([]core.Resolver, error) {
+	names := strings.Split(spec, ",")
+	chain := make([]core.Resolver, 0, len(names))
+
+	for _, name := range names {
+		name = strings.TrimSpace(strings.ToLower(name))
+		switch name {
+		case "":
+			continue
+		case "sci-hub", "scihub":
+			chain = append(chain, core.NewSciHubResolver(pool))
+		case "unpaywall":
+			chain = append(chain, core.NewUnpaywallResolver(unpaywallEmail))
+		case "crossref":
+			chain = append(chain, core.NewCrossRefResolver())
+		case "openaccessbutton":
+			chain = append(chain, core.NewOpenAccessButtonResolver())
+		default:
+			return nil, fmt.Errorf("未知的解析器: %s", name)
+		}
+	}
+
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("解析器链不能为空")
+	}
+
+	return chain, nil
+}
+
 func printCheckResult(result *core.CheckResult) {
 	fmt.Printf("\n📊 批量分析汇总:\n")
 	fmt.Printf("📁 文件总数: %d\n", result.TotalFiles)
@@ -160,7 +435,7 @@ func printCheckResult(result *core.CheckResult) {
 	// 显示每个文件的信息
 	fmt.Println("\n文件详情:")
 	for _, stats := range result.FileStats {
-		fmt.Printf("📄 %s: %d 条记录", stats.FileName, stats.TotalRecords)
+		fmt.Printf("📄 %s [%s]: %d 条记录", stats.FileName, stats.ParserName, stats.TotalRecords)
 		if stats.MissingCount > 0 {
 			fmt.Printf(" (❌ %d 条缺失 DOI)", stats.MissingCount)
 		} else {
@@ -168,6 +443,13 @@ func printCheckResult(result *core.CheckResult) {
 		}
 		fmt.Println()
 	}
+
+	if len(result.SkippedFiles) > 0 {
+		fmt.Println("\n⚠️  以下文件未能解析，已跳过:")
+		for _, skipped := range result.SkippedFiles {
+			fmt.Printf("📄 %s: %s\n", skipped.FileName, skipped.Error)
+		}
+	}
 }
 
 func printDownloadStats(stats *core.DownloadStats) {
@@ -214,6 +496,13 @@ func printDownloadStats(stats *core.DownloadStats) {
 		fmt.Printf("🚀 成功平均耗时: %s\n", formatDuration(avgSuccessTime))
 	}
 
+	if len(stats.ResolverCounts) > 0 {
+		fmt.Println("\n📚 解析器命中统计:")
+		for resolver, count := range stats.ResolverCounts {
+			fmt.Printf("  - %s: %d 个\n", resolver, count)
+		}
+	}
+
 	if len(stats.Errors) > 0 {
 		fmt.Printf("\n❌ 错误详情 (%d 个):\n", len(stats.Errors))
 		for i, err := range stats.Errors {
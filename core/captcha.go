@@ -0,0 +1,102 @@
+// core/captcha.go
+
+package core
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// 单个 DOI 上最多尝试求解验证码的次数（含首次尝试）
+const maxCaptchaAttempts = 3
+
+// CaptchaChallenge 描述一次 Sci-Hub 验证码挑战：图片内容、提交目标以及需要随表单
+// 一起回传的隐藏字段。AnswerField 是验证码答案对应的表单输入框 name。
+type CaptchaChallenge struct {
+	ImageBytes  []byte
+	FormAction  string
+	FormMethod  string
+	AnswerField string
+	ExtraFields map[string]string
+}
+
+// CaptchaSolver 把验证码图片识别为文本。实现需要是无状态或自行保证并发安全，
+// 因为同一个 solver 实例会被多个下载 worker 共享。
+type CaptchaSolver interface {
+	Name() string
+	Solve(challenge CaptchaChallenge) (string, error)
+}
+
+// NoopCaptchaSolver 是默认实现：不尝试识别，直接报错，等价于"遇到验证码就放弃"，
+// 与引入本功能之前的行为一致。
+type NoopCaptchaSolver struct{}
+
+func (NoopCaptchaSolver) Name() string { return "noop" }
+
+func (NoopCaptchaSolver) Solve(CaptchaChallenge) (string, error) {
+	return "", fmt.Errorf("未配置验证码求解器")
+}
+
+// HTTPCaptchaSolver 把验证码图片以 base64 编码 POST 给外部识别服务，并读取其返回的识别结果。
+// 外部服务约定：请求体 {"image_base64": "..."},响应体 {"answer": "..."}。
+type HTTPCaptchaSolver struct {
+	Endpoint string
+	Client   *http.Client
+}
+
+// NewHTTPCaptchaSolver 创建一个基于外部 HTTP 识别服务的验证码求解器
+func NewHTTPCaptchaSolver(endpoint string) *HTTPCaptchaSolver {
+	return &HTTPCaptchaSolver{
+		Endpoint: endpoint,
+		Client:   &http.Client{},
+	}
+}
+
+func (s *HTTPCaptchaSolver) Name() string { return "http" }
+
+func (s *HTTPCaptchaSolver) Solve(challenge CaptchaChallenge) (string, error) {
+	if s.Endpoint == "" {
+		return "", fmt.Errorf("http 验证码求解器未配置 endpoint")
+	}
+
+	reqBody, err := json.Marshal(struct {
+		ImageBase64 string `json:"image_base64"`
+	}{
+		ImageBase64: base64.StdEncoding.EncodeToString(challenge.ImageBytes),
+	})
+	if err != nil {
+		return "", fmt.Errorf("序列化验证码识别请求失败: %v", err)
+	}
+
+	resp, err := s.Client.Post(s.Endpoint, "application/json", bytes.NewReader(reqBody))
+	if err != nil {
+		return "", fmt.Errorf("验证码识别服务请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("验证码识别服务返回 HTTP %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("读取验证码识别服务响应失败: %v", err)
+	}
+
+	var result struct {
+		Answer string `json:"answer"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("解析验证码识别服务响应失败: %v", err)
+	}
+
+	if result.Answer == "" {
+		return "", fmt.Errorf("验证码识别服务未返回识别结果")
+	}
+
+	return result.Answer, nil
+}
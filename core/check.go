@@ -7,7 +7,6 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"regexp"
 	"sort"
 	"strings"
 )
@@ -15,6 +14,7 @@ import (
 // FileStats 文件统计信息
 type FileStats struct {
 	FileName       string
+	ParserName     string // 实际用于解析该文件的解析器名称（wos-txt、ris、bibtex……）
 	TotalRecords   int
 	ValidDOIs      int
 	MissingCount   int
@@ -38,35 +38,50 @@ type CheckResult struct {
 	FileStats     []FileStats
 	AllDOIs       []string
 	DuplicateDOIs map[string]map[string]int // DOI -> filename -> count
+	SkippedFiles  []SkippedFile              // 解析失败而被跳过的文件（如损坏的 .sqlite 库），附带原因
 }
 
-// CheckDOIs 检查 archive 目录下的所有文件中的 DOI 记录
+// SkippedFile 记录一个因解析出错而未能纳入统计的输入文件
+type SkippedFile struct {
+	FileName string
+	Error    string
+}
+
+// CheckDOIs 检查 archive 目录下所有已识别文件格式的 DOI 记录
 func CheckDOIs(archiveDir string) (*CheckResult, error) {
+	return CheckDOIsWithOptions(archiveDir, ParseOptions{})
+}
+
+// CheckDOIsWithOptions 与 CheckDOIs 相同，但允许传入解析器选项（如 CSV 的 --doi-column）
+func CheckDOIsWithOptions(archiveDir string, opts ParseOptions) (*CheckResult, error) {
 	// 检查目录是否存在
 	if _, err := os.Stat(archiveDir); os.IsNotExist(err) {
 		return nil, fmt.Errorf("目录不存在: %s", archiveDir)
 	}
 
-	// 获取所有 .txt 文件
-	txtFiles, err := getTxtFiles(archiveDir)
+	inputFiles, err := getInputFiles(archiveDir, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(txtFiles) == 0 {
-		return nil, fmt.Errorf("%s 下没有 .txt 文件", archiveDir)
+	if len(inputFiles) == 0 {
+		return nil, fmt.Errorf("%s 下没有可识别的书目文件", archiveDir)
 	}
 
 	result := &CheckResult{
 		FileStats:     make([]FileStats, 0),
 		AllDOIs:       make([]string, 0),
 		DuplicateDOIs: make(map[string]map[string]int),
+		SkippedFiles:  make([]SkippedFile, 0),
 	}
 
 	// 处理每个文件
-	for _, filePath := range txtFiles {
-		stats, err := analyzeFile(filePath)
+	for _, filePath := range inputFiles {
+		stats, records, err := analyzeFile(filePath, opts)
 		if err != nil {
+			result.SkippedFiles = append(result.SkippedFiles, SkippedFile{
+				FileName: filepath.Base(filePath), Error: err.Error(),
+			})
 			continue
 		}
 
@@ -75,16 +90,16 @@ func CheckDOIs(archiveDir string) (*CheckResult, error) {
 		result.TotalDOIs += stats.ValidDOIs
 		result.MissingDOIs += stats.MissingCount
 
-		// 收集所有 DOI
-		dois := extractDOIsFromFile(filePath)
-		for _, doi := range dois {
-			result.AllDOIs = append(result.AllDOIs, doi)
-			// 记录 DOI 出现的文件
-			if result.DuplicateDOIs[doi] == nil {
-				result.DuplicateDOIs[doi] = make(map[string]int)
+		fileName := filepath.Base(filePath)
+		for _, rec := range records {
+			if rec.DOI == "" {
+				continue
+			}
+			result.AllDOIs = append(result.AllDOIs, rec.DOI)
+			if result.DuplicateDOIs[rec.DOI] == nil {
+				result.DuplicateDOIs[rec.DOI] = make(map[string]int)
 			}
-			fileName := filepath.Base(filePath)
-			result.DuplicateDOIs[doi][fileName]++
+			result.DuplicateDOIs[rec.DOI][fileName]++
 		}
 	}
 
@@ -94,7 +109,7 @@ func CheckDOIs(archiveDir string) (*CheckResult, error) {
 		uniqueDOIs[doi] = true
 	}
 	result.UniqueDOIs = len(uniqueDOIs)
-	result.TotalFiles = len(txtFiles)
+	result.TotalFiles = len(inputFiles)
 
 	// 计算覆盖率
 	if result.TotalRecords > 0 {
@@ -104,27 +119,34 @@ func CheckDOIs(archiveDir string) (*CheckResult, error) {
 	return result, nil
 }
 
-// ExtractDOIs 从 archive 目录提取所有有效的 DOI
+// ExtractDOIs 从 archive 目录提取所有有效的、去重后的 DOI
 func ExtractDOIs(archiveDir string) ([]string, error) {
+	return ExtractDOIsWithOptions(archiveDir, ParseOptions{})
+}
+
+// ExtractDOIsWithOptions 与 ExtractDOIs 相同，但允许传入解析器选项
+func ExtractDOIsWithOptions(archiveDir string, opts ParseOptions) ([]string, error) {
 	if _, err := os.Stat(archiveDir); os.IsNotExist(err) {
 		return nil, fmt.Errorf("目录不存在: %s", archiveDir)
 	}
 
-	txtFiles, err := getTxtFiles(archiveDir)
+	inputFiles, err := getInputFiles(archiveDir, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	allDOIs := make([]string, 0)
-	for _, filePath := range txtFiles {
-		dois := extractDOIsFromFile(filePath)
-		allDOIs = append(allDOIs, dois...)
-	}
-
-	// 去重
 	uniqueDOIs := make(map[string]bool)
-	for _, doi := range allDOIs {
-		uniqueDOIs[doi] = true
+	for _, filePath := range inputFiles {
+		_, records, err := analyzeFile(filePath, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  跳过 %s: %v\n", filepath.Base(filePath), err)
+			continue
+		}
+		for _, rec := range records {
+			if rec.DOI != "" {
+				uniqueDOIs[rec.DOI] = true
+			}
+		}
 	}
 
 	result := make([]string, 0, len(uniqueDOIs))
@@ -137,102 +159,85 @@ func ExtractDOIs(archiveDir string) ([]string, error) {
 
 // 辅助函数
 
-func getTxtFiles(dir string) ([]string, error) {
+// getInputFiles 列出 dir 下扩展名在白名单内的文件；extensions 为空时使用所有已注册解析器的扩展名。
+func getInputFiles(dir string, extensions []string) ([]string, error) {
 	files, err := ioutil.ReadDir(dir)
 	if err != nil {
 		return nil, err
 	}
 
-	txtFiles := make([]string, 0)
+	if len(extensions) == 0 {
+		extensions = registeredExtensions()
+	}
+	allowed := make(map[string]bool, len(extensions))
+	for _, e := range extensions {
+		allowed[strings.ToLower(e)] = true
+	}
+
+	inputFiles := make([]string, 0)
 	for _, file := range files {
-		if !file.IsDir() && strings.HasSuffix(file.Name(), ".txt") {
-			txtFiles = append(txtFiles, filepath.Join(dir, file.Name()))
+		if file.IsDir() {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(file.Name()))
+		if allowed[ext] {
+			inputFiles = append(inputFiles, filepath.Join(dir, file.Name()))
 		}
 	}
 
-	sort.Strings(txtFiles)
-	return txtFiles, nil
+	sort.Strings(inputFiles)
+	return inputFiles, nil
 }
 
-func analyzeFile(filePath string) (*FileStats, error) {
-	content, err := readFileText(filePath)
-	if err != nil {
-		return nil, err
+// analyzeFile 选出合适的 RecordParser 并解析出该文件的所有记录
+func analyzeFile(filePath string, opts ParseOptions) (*FileStats, []Record, error) {
+	ext := strings.ToLower(filepath.Ext(filePath))
+
+	var records []Record
+	var parserName string
+
+	if ext == ".sqlite" {
+		zoteroRecords, err := extractZoteroRecords(filePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		records = zoteroRecords
+		parserName = (&zoteroSQLiteParser{}).Name()
+	} else {
+		content, err := readFileText(filePath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		parser := detectParser(filePath, content)
+		if parser == nil {
+			return nil, nil, fmt.Errorf("无法识别文件格式: %s", filePath)
+		}
+
+		records = parser.ParseRecords(content, opts)
+		parserName = parser.Name()
 	}
 
-	records := parseWosRecords(content)
 	stats := &FileStats{
 		FileName:       filepath.Base(filePath),
+		ParserName:     parserName,
 		TotalRecords:   len(records),
 		MissingDetails: make([]MissingRecord, 0),
 	}
 
-	doiRegex := regexp.MustCompile(`^10\.\d{4,9}/[^\s]+$`)
-
-	for idx, lines := range records {
-		doi := extractDOIFromRecord(lines)
-		if doi != "" && doiRegex.MatchString(doi) {
+	for idx, rec := range records {
+		if rec.DOI != "" {
 			stats.ValidDOIs++
 		} else {
 			stats.MissingCount++
 			stats.MissingDetails = append(stats.MissingDetails, MissingRecord{
 				Index:   idx,
-				Content: strings.Join(lines, "\n"),
+				Content: strings.Join(rec.Lines, "\n"),
 			})
 		}
 	}
 
-	return stats, nil
-}
-
-func parseWosRecords(text string) [][]string {
-	blocks := strings.Split(text, "\nER\n")
-	records := make([][]string, 0)
-
-	for _, block := range blocks {
-		block = strings.TrimSpace(block)
-		if block == "" || block == "EF" || (strings.HasPrefix(block, "EF") && len(strings.Fields(block)) == 1) {
-			continue
-		}
-
-		lines := strings.Split(block, "\n")
-		lines = append(lines, "ER")
-		records = append(records, lines)
-	}
-
-	return records
-}
-
-func extractDOIFromRecord(lines []string) string {
-	for _, line := range lines {
-		if strings.HasPrefix(line, "DI") {
-			parts := strings.Fields(line)
-			if len(parts) >= 2 {
-				return strings.Join(parts[1:], " ")
-			}
-		}
-	}
-	return ""
-}
-
-func extractDOIsFromFile(filePath string) []string {
-	content, err := readFileText(filePath)
-	if err != nil {
-		return nil
-	}
-
-	records := parseWosRecords(content)
-	dois := make([]string, 0)
-	doiRegex := regexp.MustCompile(`^10\.\d{4,9}/[^\s]+$`)
-
-	for _, lines := range records {
-		doi := extractDOIFromRecord(lines)
-		if doi != "" && doiRegex.MatchString(doi) {
-			dois = append(dois, doi)
-		}
-	}
-
-	return dois
+	return stats, records, nil
 }
 
 func readFileText(filePath string) (string, error) {
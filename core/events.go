@@ -0,0 +1,105 @@
+// core/events.go
+
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+// Event 是 EventBus 上流转的统一事件类型，具体事件通过类型断言区分
+type Event interface {
+	isEvent()
+}
+
+// JobQueued 表示一个 DOI 已进入下载队列
+type JobQueued struct {
+	DOI string
+}
+
+// JobStarted 表示一个 worker 开始处理某个 DOI
+type JobStarted struct {
+	DOI string
+}
+
+// JobSucceeded 表示某个 DOI 下载成功
+type JobSucceeded struct {
+	DOI     string
+	Bytes   int64
+	Elapsed time.Duration
+}
+
+// JobFailed 表示某个 DOI 最终下载失败
+type JobFailed struct {
+	DOI    string
+	Err    string
+	Mirror string
+}
+
+// MirrorQuarantined 表示某个镜像因窗口内累计的 403/验证码失败达到阈值而被隔离
+type MirrorQuarantined struct {
+	Mirror string
+}
+
+func (JobQueued) isEvent()         {}
+func (JobStarted) isEvent()        {}
+func (JobSucceeded) isEvent()      {}
+func (JobFailed) isEvent()         {}
+func (MirrorQuarantined) isEvent() {}
+
+// EventBus 是一个简单的多订阅者广播总线，供 TUI 进度条、Prometheus exporter、
+// SSE `/events` 端点等下游消费者订阅，而核心下载逻辑无需知道这些消费者的存在。
+type EventBus struct {
+	mu          sync.Mutex
+	subscribers []chan Event
+}
+
+// NewEventBus 创建一个空的事件总线
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe 注册一个新的订阅者，返回一个只读事件 channel。
+// channel 带缓冲，消费者处理不及时时新事件会被丢弃，而不会拖慢下载主流程。
+func (b *EventBus) Subscribe(bufferSize int) <-chan Event {
+	if bufferSize <= 0 {
+		bufferSize = 64
+	}
+	ch := make(chan Event, bufferSize)
+
+	b.mu.Lock()
+	b.subscribers = append(b.subscribers, ch)
+	b.mu.Unlock()
+
+	return ch
+}
+
+// publish 向所有订阅者广播一个事件；b 为 nil 时是安全的空操作，
+// 这样核心逻辑可以无条件调用 publish 而不必在每处判空。
+func (b *EventBus) publish(e Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+			// 订阅者消费太慢，丢弃事件而不是阻塞下载流水线
+		}
+	}
+}
+
+// Close 关闭所有订阅者 channel，不再接受新的发布
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, ch := range b.subscribers {
+		close(ch)
+	}
+	b.subscribers = nil
+}
@@ -0,0 +1,177 @@
+// core/history.go
+
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const historyFileName = "history.json"
+
+// historyBaseBackoff、historyMaxBackoff 决定失败 DOI 的指数退避重试间隔：
+// 第 N 次失败后的退避时长为 min(historyBaseBackoff * 2^(N-1), historyMaxBackoff)
+const (
+	historyBaseBackoff = 30 * time.Second
+	historyMaxBackoff  = 2 * time.Hour
+)
+
+// HistoryEntry 记录某个 DOI 历史上的处理结果，独立于 Manifest：
+// Manifest 只关心"文件是否还在磁盘上且未损坏"，History 还记录失败次数与下次可重试时间，
+// 用于在大批量增量运行中把已知失败的 DOI 排到重试队列尾部，而不是每次都重新打一遍网络请求。
+type HistoryEntry struct {
+	DOI         string    `json:"doi"`
+	Status      string    `json:"status"` // success, failed
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastAttempt time.Time `json:"last_attempt"`
+	NextRetryAt time.Time `json:"next_retry_at,omitempty"`
+}
+
+// History 是跨运行持久化的 DOI 处理历史，按 DOI 的 SHA-256 哈希为 key 存储
+type History struct {
+	path string
+
+	mu      sync.Mutex
+	Entries map[string]HistoryEntry `json:"entries"`
+}
+
+// NewHistory 加载 pdfDir/.doihive/history.json，不存在时返回一个空历史
+func NewHistory(pdfDir string) (*History, error) {
+	dir := filepath.Join(pdfDir, manifestDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("无法创建历史记录目录: %v", err)
+	}
+
+	h := &History{
+		path:    filepath.Join(dir, historyFileName),
+		Entries: make(map[string]HistoryEntry),
+	}
+
+	data, err := os.ReadFile(h.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return h, nil
+		}
+		return nil, fmt.Errorf("无法读取历史记录文件: %v", err)
+	}
+
+	if len(data) == 0 {
+		return h, nil
+	}
+
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("历史记录文件解析失败: %v", err)
+	}
+	if h.Entries == nil {
+		h.Entries = make(map[string]HistoryEntry)
+	}
+
+	return h, nil
+}
+
+// save 原子地将历史记录写回磁盘：先写临时文件，再 rename 覆盖（调用方需持有 h.mu）
+func (h *History) save() error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return fmt.Errorf("历史记录序列化失败: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(h.path), "history-*.tmp")
+	if err != nil {
+		return fmt.Errorf("无法创建临时历史记录文件: %v", err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("写入临时历史记录文件失败: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("关闭临时历史记录文件失败: %v", err)
+	}
+
+	if err := os.Rename(tmpPath, h.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("替换历史记录文件失败: %v", err)
+	}
+
+	return nil
+}
+
+// historyKey 返回 DOI 对应的哈希 key，避免 DOI 中的 "/" 等字符给人一种可以直接当文件名用的错觉
+func historyKey(doi string) string {
+	sum := sha256.Sum256([]byte(doi))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get 返回给定 DOI 的历史记录
+func (h *History) Get(doi string) (HistoryEntry, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entry, ok := h.Entries[historyKey(doi)]
+	return entry, ok
+}
+
+// ShouldSkip 判断某个 DOI 是否因历史上已成功过而可以跳过（不依赖当前磁盘上的文件是否还在，
+// 调用方通常会与 Manifest.ShouldSkip 搭配使用，两者任一为 true 即可跳过）
+func (h *History) ShouldSkip(doi string) bool {
+	entry, ok := h.Get(doi)
+	return ok && entry.Status == "success"
+}
+
+// ShouldRetryNow 判断某个曾经失败过的 DOI 当前是否已经过了退避期，可以重新尝试；
+// 没有历史记录或历史记录是 success 时也返回 true（不归本方法处理的场景应提前用 ShouldSkip 过滤）
+func (h *History) ShouldRetryNow(doi string) bool {
+	entry, ok := h.Get(doi)
+	if !ok || entry.Status == "success" {
+		return true
+	}
+	return !time.Now().Before(entry.NextRetryAt)
+}
+
+// backoffDuration 返回第 attempts 次失败后的退避时长
+func backoffDuration(attempts int) time.Duration {
+	d := historyBaseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= historyMaxBackoff {
+			return historyMaxBackoff
+		}
+	}
+	return d
+}
+
+// Record 记录一个 DOI 的最终结果并立即原子落盘；失败时按指数退避计算下次可重试时间。
+func (h *History) Record(doi string, success bool, errMsg string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := historyKey(doi)
+	entry := h.Entries[key]
+	entry.DOI = doi
+	entry.Attempts++
+	entry.LastAttempt = time.Now()
+
+	if success {
+		entry.Status = "success"
+		entry.LastError = ""
+		entry.NextRetryAt = time.Time{}
+	} else {
+		entry.Status = "failed"
+		entry.LastError = errMsg
+		entry.NextRetryAt = entry.LastAttempt.Add(backoffDuration(entry.Attempts))
+	}
+
+	h.Entries[key] = entry
+
+	return h.save()
+}
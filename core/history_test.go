@@ -0,0 +1,78 @@
+// core/history_test.go
+
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDurationGrowsExponentiallyAndCaps(t *testing.T) {
+	cases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, historyBaseBackoff},
+		{2, 2 * historyBaseBackoff},
+		{3, 4 * historyBaseBackoff},
+		{4, 8 * historyBaseBackoff},
+	}
+	for _, c := range cases {
+		if got := backoffDuration(c.attempts); got != c.want {
+			t.Errorf("backoffDuration(%d) = %v, want %v", c.attempts, got, c.want)
+		}
+	}
+
+	// 足够多的失败次数后必须封顶在 historyMaxBackoff，不能无限翻倍下去
+	if got := backoffDuration(20); got != historyMaxBackoff {
+		t.Errorf("backoffDuration(20) = %v, want capped at %v", got, historyMaxBackoff)
+	}
+}
+
+func TestHistoryShouldRetryNow(t *testing.T) {
+	h, err := NewHistory(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewHistory() error = %v", err)
+	}
+
+	const doi = "10.1000/retry-test"
+
+	// 从未记录过的 DOI 应该可以立即重试
+	if !h.ShouldRetryNow(doi) {
+		t.Fatalf("ShouldRetryNow() = false for unknown DOI, want true")
+	}
+
+	if err := h.Record(doi, false, "403"); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	// 刚失败一次，退避期还没过，不应该允许立即重试
+	if h.ShouldRetryNow(doi) {
+		t.Fatalf("ShouldRetryNow() = true right after a failure, want false")
+	}
+
+	entry, ok := h.Get(doi)
+	if !ok {
+		t.Fatalf("Get() ok = false after Record()")
+	}
+	if entry.Status != "failed" || entry.Attempts != 1 {
+		t.Fatalf("entry after first failure = %+v, want status=failed attempts=1", entry)
+	}
+
+	// 手动把 NextRetryAt 拨到过去，模拟退避期已过
+	entry.NextRetryAt = time.Now().Add(-time.Second)
+	h.mu.Lock()
+	h.Entries[historyKey(doi)] = entry
+	h.mu.Unlock()
+
+	if !h.ShouldRetryNow(doi) {
+		t.Fatalf("ShouldRetryNow() = false after backoff expired, want true")
+	}
+
+	if err := h.Record(doi, true, ""); err != nil {
+		t.Fatalf("Record(success) error = %v", err)
+	}
+	if !h.ShouldSkip(doi) {
+		t.Fatalf("ShouldSkip() = false after a success record, want true")
+	}
+}
@@ -4,6 +4,7 @@ package core
 
 import (
 	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
@@ -13,7 +14,6 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
@@ -21,25 +21,29 @@ import (
 
 // DownloadResult 下载结果
 type DownloadResult struct {
-	Status   string // success, skip, failed
-	Filename string
-	Size     int64
-	DOI      string
-	Error    string
-	Duration time.Duration
+	Status       string // success, skip, failed
+	Filename     string
+	Size         int64
+	DOI          string
+	URL          string // 实际请求的镜像页面 URL
+	ResolverName string // 最终产出 PDF 的解析器名称（sci-hub、unpaywall、crossref…）
+	Error        string
+	Duration     time.Duration
 }
 
 // DownloadStats 下载统计
 type DownloadStats struct {
-	Total       int
-	Success     int
-	Skip        int
-	Failed      int
-	TotalSize   int64
-	Errors      []DownloadError
-	TotalTime   time.Duration   // 总耗时（墙钟时间）
-	AllTimes    []time.Duration // 所有任务的时间（包括成功、失败、跳过）
-	SuccessTime []time.Duration // 成功任务的时间
+	Total          int
+	Success        int
+	Skip           int
+	Failed         int
+	TotalSize      int64
+	Errors         []DownloadError
+	TotalTime      time.Duration        // 总耗时（墙钟时间）
+	AllTimes       []time.Duration      // 所有任务的时间（包括成功、失败、跳过）
+	SuccessTime    []time.Duration      // 成功任务的时间
+	Mirrors        []MirrorStatSnapshot // 各镜像的健康状况快照（末尾采集）
+	ResolverCounts map[string]int       // 每个解析器最终产出 PDF 的次数
 }
 
 // DownloadError 下载错误信息
@@ -50,109 +54,259 @@ type DownloadError struct {
 	Time  time.Time
 }
 
-// DownloadPDFs 批量下载 PDF 文件
-func DownloadPDFs(urls []string, pdfDir string, maxWorkers int) (*DownloadStats, error) {
-	// 确保输出目录存在
-	if err := os.MkdirAll(pdfDir, 0755); err != nil {
-		return nil, fmt.Errorf("无法创建 PDF 目录: %v", err)
+// DownloadPDFs 批量下载 PDF 文件，opts 描述镜像池、清单、解析器链、验证码求解器等配置。
+// 这是 RunPipeline 的便捷封装：把一个已知的 DOI 切片包装成 channel 后交给流水线处理，
+// 调用方若要处理百万级、无法一次性放进内存的 DOI 集合，应直接调用 RunPipeline。
+func DownloadPDFs(dois []string, opts PipelineOptions) (*DownloadStats, error) {
+	source := make(chan DOIJob, len(dois))
+	for _, doi := range dois {
+		source <- DOIJob{DOI: doi}
 	}
+	close(source)
 
-	stats := &DownloadStats{
-		Total:       len(urls),
-		Errors:      make([]DownloadError, 0),
-		AllTimes:    make([]time.Duration, 0),
-		SuccessTime: make([]time.Duration, 0),
+	return RunPipeline(source, opts)
+}
+
+// mirrorFailureReason 从下载结果的错误信息中归类出镜像池关心的失败原因，
+// 只有 "403" 和 "captcha" 会触发隔离计数，其余失败原因仅计入连续失败次数。
+func mirrorFailureReason(result DownloadResult) string {
+	if result.Status == "success" {
+		return ""
+	}
+	lowerErr := strings.ToLower(result.Error)
+	if strings.Contains(result.Error, "403") {
+		return "403"
 	}
+	if strings.Contains(lowerErr, "captcha") || strings.Contains(result.Error, "验证码") {
+		return "captcha"
+	}
+	return "other"
+}
 
-	// 创建复用的 HTTP 客户端（带连接池优化）
-	transport := &http.Transport{
-		MaxIdleConns:        maxWorkers * 2, // 最大空闲连接数
-		MaxIdleConnsPerHost: maxWorkers,     // 每个主机的最大空闲连接数
-		MaxConnsPerHost:     maxWorkers * 2, // 每个主机的最大连接数（包括正在使用的）
-		IdleConnTimeout:     90 * time.Second,
-		DisableKeepAlives:   false, // 启用连接复用
-		// 启用 HTTP/2（如果服务器支持，可以提升性能）
-		ForceAttemptHTTP2: true,
+// resolveAndDownload 依次尝试 resolvers 链中的每个解析器，直到某一个产出可下载的 PDF。
+// 返回最终结果，以及用于写入清单 SourceMirror 字段的来源标签（Sci-Hub 镜像 URL 或解析器名称）。
+// bus 可为 nil：镜像被隔离时会向其发布 MirrorQuarantined 事件。
+func resolveAndDownload(doi string, resolvers []Resolver, pool *MirrorPool, pdfDir string, client, pdfClient *http.Client, bus *EventBus, solver CaptchaSolver, proxies *ProxyPool, progress chan<- ProgressEvent, logger StructuredLogger) (DownloadResult, string) {
+	var lastResult DownloadResult
+	attempted := false
+
+	for _, resolver := range resolvers {
+		candidate, err := resolver.Resolve(doi, client)
+		if err != nil || candidate.URL == "" {
+			continue
+		}
+		attempted = true
+
+		result, sourceLabel := attemptWithMirrorFailover(doi, resolver, candidate, pool, pdfDir, client, pdfClient, bus, solver, proxies, progress, logger)
+		if result.Status == "success" {
+			return result, sourceLabel
+		}
+		lastResult = result
 	}
 
-	sharedClient := &http.Client{
-		Transport: transport,
-		Timeout:   10 * time.Second, // 页面请求超时
+	if !attempted {
+		lastResult = DownloadResult{Status: "failed", DOI: doi, Error: "所有解析器均未能返回候选下载地址"}
 	}
 
-	pdfClient := &http.Client{
-		Transport: transport,
-		Timeout:   30 * time.Second, // PDF 下载超时
+	return lastResult, lastResult.ResolverName
+}
+
+// attemptWithMirrorFailover 下载单个候选地址；当候选由镜像池提供（candidate.Mirror != nil）
+// 且本次尝试因 403/404/超时失败时，在同一个 DOI 上切换到池中下一个健康镜像重试，
+// 最多尝试镜像池大小那么多次，避免单个镜像的暂时性故障被当成该 DOI 彻底失败。
+// 非镜像来源的候选（如 Unpaywall/CrossRef 直链）只尝试一次。
+func attemptWithMirrorFailover(doi string, resolver Resolver, candidate ResolverCandidate, pool *MirrorPool, pdfDir string, client, pdfClient *http.Client, bus *EventBus, solver CaptchaSolver, proxies *ProxyPool, progress chan<- ProgressEvent, logger StructuredLogger) (DownloadResult, string) {
+	sourceLabel := resolver.Name()
+
+	maxAttempts := 1
+	if candidate.Mirror != nil && pool != nil {
+		if n := pool.Len(); n > maxAttempts {
+			maxAttempts = n
+		}
 	}
 
-	// 创建 worker pool
-	type jobWithTime struct {
-		url       string
-		startTime time.Time
+	var result DownloadResult
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		attemptStart := time.Now()
+		if candidate.Kind == "page" {
+			result = downloadSinglePDF(candidate.URL, pdfDir, client, pdfClient, solver, proxies, progress, logger, attempt+1)
+		} else {
+			result = downloadDirectPDF(candidate.URL, doi, pdfDir, pdfClient, proxies, progress, attempt+1)
+		}
+		result.URL = candidate.URL
+		result.ResolverName = resolver.Name()
+
+		if candidate.Mirror == nil {
+			return result, sourceLabel
+		}
+
+		reason := mirrorFailureReason(result)
+		quarantined := pool.RecordResult(candidate.Mirror, result.Status == "success", time.Since(attemptStart), reason)
+		if quarantined {
+			bus.publish(MirrorQuarantined{Mirror: candidate.Mirror.BaseURL})
+		}
+		sourceLabel = candidate.Mirror.BaseURL
+
+		if result.Status == "success" {
+			return result, sourceLabel
+		}
+
+		articleUnavailable := strings.Contains(result.Error, "不可用")
+		if articleUnavailable {
+			pool.MarkUnavailable(candidate.Mirror, doi)
+		}
+
+		lowerErr := strings.ToLower(result.Error)
+		shouldFailover := reason == "403" || strings.Contains(result.Error, "404") ||
+			strings.Contains(lowerErr, "timeout") || strings.Contains(result.Error, "超时")
+		if !shouldFailover && !articleUnavailable {
+			return result, sourceLabel
+		}
+
+		if !articleUnavailable {
+			// 暂时把该镜像从这个 DOI 的候选里排除，让下一轮 BestNonBlocking 选到别的镜像
+			pool.MarkUnavailable(candidate.Mirror, doi)
+		}
+
+		next := pool.BestNonBlocking(doi)
+		if next == nil {
+			return result, sourceLabel
+		}
+		candidate = ResolverCandidate{Kind: candidate.Kind, URL: mirrorPageURL(next, doi), Mirror: next}
 	}
-	jobs := make(chan jobWithTime, len(urls))
-	results := make(chan DownloadResult, len(urls))
 
-	// 启动 workers
-	var wg sync.WaitGroup
-	for i := 0; i < maxWorkers; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for job := range jobs {
-				result := downloadSinglePDF(job.url, pdfDir, sharedClient, pdfClient)
-				// 计算从提交到完成的总时间（包括等待时间）
-				result.Duration = time.Since(job.startTime)
-				results <- result
-			}
-		}()
+	return result, sourceLabel
+}
+
+// clientForAttempt 为一次请求挑选出口：proxies 为 nil 时直接复用 base；否则向代理池要一个代理
+// （可能是 nil 代表直连回退），克隆 base 的 Transport 并应用该代理。返回的 proxy 供调用方在
+// 请求结束后回报给 RecordResult，ok 为 false 表示代理池配置了禁止直连且当前无可用代理。
+func clientForAttempt(base *http.Client, proxies *ProxyPool) (attemptClient *http.Client, proxy *Proxy, ok bool) {
+	if proxies == nil {
+		return base, nil, true
+	}
+
+	p, ok := proxies.Next()
+	if !ok {
+		return nil, nil, false
+	}
+	if p == nil {
+		return base, nil, true
 	}
 
-	// 开始计时（在发送任务之前）
-	startTime := time.Now()
+	baseTransport, _ := base.Transport.(*http.Transport)
+	if baseTransport == nil {
+		baseTransport = &http.Transport{}
+	}
+	return &http.Client{Transport: transportFor(baseTransport, p), Timeout: base.Timeout, Jar: base.Jar}, p, true
+}
 
-	// 发送任务（记录每个任务的提交时间）
-	go func() {
-		for _, u := range urls {
-			jobs <- jobWithTime{
-				url:       u,
-				startTime: time.Now(),
-			}
+// downloadDirectPDF 直接下载一个已知的 PDF URL（由 Unpaywall/CrossRef/Open Access Button 等解析器给出），
+// 跳过 Sci-Hub 风格的 HTML 抓取与链接提取步骤。
+func downloadDirectPDF(pdfURL string, doi string, pdfDir string, pdfClient *http.Client, proxies *ProxyPool, progress chan<- ProgressEvent, attempt int) DownloadResult {
+	createResult := func(status, filename string, size int64, errMsg string) DownloadResult {
+		return DownloadResult{Status: status, Filename: filename, Size: size, DOI: doi, Error: errMsg}
+	}
+
+	safeFilename := strings.ReplaceAll(doi, "/", "_")
+	safeFilename = strings.ReplaceAll(safeFilename, ":", "_")
+	pdfFilename := safeFilename + ".pdf"
+	pdfFilePath := filepath.Join(pdfDir, pdfFilename)
+
+	if info, err := os.Stat(pdfFilePath); err == nil {
+		return createResult("skip", pdfFilename, info.Size(), "")
+	}
+
+	req, err := http.NewRequest("GET", pdfURL, nil)
+	if err != nil {
+		return createResult("failed", pdfFilename, 0, fmt.Sprintf("创建请求失败: %v", err))
+	}
+	setBrowserHeaders(req)
+
+	publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageDownloading, Attempt: attempt})
+
+	attemptClient, proxy, ok := clientForAttempt(pdfClient, proxies)
+	if !ok {
+		publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
+		return createResult("failed", pdfFilename, 0, "所有代理均不可用")
+	}
+
+	resp, err := attemptClient.Do(req)
+	if err != nil {
+		proxies.RecordResult(proxy, false, "conn")
+		publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
+		return createResult("failed", pdfFilename, 0, fmt.Sprintf("PDF 下载失败: %v", err))
+	}
+
+	if resp.StatusCode == http.StatusForbidden {
+		resp.Body.Close()
+		proxies.RecordResult(proxy, false, "403")
+		publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
+		return createResult("failed", pdfFilename, 0, fmt.Sprintf("PDF 下载失败: HTTP %d", resp.StatusCode))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		proxies.RecordResult(proxy, false, "other")
+		publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
+		return createResult("failed", pdfFilename, 0, fmt.Sprintf("PDF 下载失败: HTTP %d", resp.StatusCode))
+	}
+	proxies.RecordResult(proxy, true, "")
+
+	tmpFile, err := os.CreateTemp(pdfDir, "*.tmp")
+	if err != nil {
+		return createResult("failed", pdfFilename, 0, fmt.Sprintf("创建临时文件失败: %v", err))
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	bytesTotal := resp.ContentLength
+	// countingReader 包在最底层的 resp.Body 外面：即使后面套了一层 gzip 解压，
+	// 这里汇报的仍是实际从网络读到的字节数，与进度条/限速观察到的吞吐量对得上
+	var reader io.Reader = &countingReader{r: resp.Body, onRead: func(done int64) {
+		publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageDownloading, BytesDone: done, BytesTotal: bytesTotal, Attempt: attempt})
+	}}
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gzReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return createResult("failed", pdfFilename, 0, fmt.Sprintf("PDF 解压缩失败: %v", err))
 		}
-		close(jobs)
-	}()
-
-	// 等待所有 workers 完成
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
-
-	// 收集结果
-	for result := range results {
-		// 记录所有任务的时间（包括成功、失败、跳过）
-		stats.AllTimes = append(stats.AllTimes, result.Duration)
-
-		switch result.Status {
-		case "success":
-			stats.Success++
-			stats.TotalSize += result.Size
-			stats.SuccessTime = append(stats.SuccessTime, result.Duration)
-		case "skip":
-			stats.Skip++
-		case "failed":
-			stats.Failed++
-			stats.Errors = append(stats.Errors, DownloadError{
-				URL:   fmt.Sprintf("https://sci-hub.se/%s", result.DOI),
-				DOI:   result.DOI,
-				Error: result.Error,
-				Time:  time.Now(),
-			})
-		}
-	}
-	stats.TotalTime = time.Since(startTime)
-
-	return stats, nil
+		defer gzReader.Close()
+		reader = gzReader
+	}
+
+	written, err := io.Copy(tmpFile, reader)
+	tmpFile.Close()
+	if err != nil {
+		return createResult("failed", pdfFilename, 0, fmt.Sprintf("写入文件失败: %v", err))
+	}
+	if written == 0 {
+		return createResult("failed", pdfFilename, 0, "下载的文件大小为 0")
+	}
+
+	publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageVerifying, BytesDone: written, BytesTotal: bytesTotal, Attempt: attempt})
+
+	file, err := os.Open(tmpPath)
+	if err != nil {
+		return createResult("failed", pdfFilename, 0, fmt.Sprintf("打开文件失败: %v", err))
+	}
+	header := make([]byte, 4)
+	_, readErr := file.Read(header)
+	file.Close()
+	if readErr != nil {
+		return createResult("failed", pdfFilename, 0, fmt.Sprintf("读取文件头失败: %v", readErr))
+	}
+	if string(header) != "%PDF" {
+		publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
+		return createResult("failed", pdfFilename, 0, "下载的文件不是有效的 PDF 文件")
+	}
+
+	if err := os.Rename(tmpPath, pdfFilePath); err != nil {
+		return createResult("failed", pdfFilename, 0, fmt.Sprintf("移动文件失败: %v", err))
+	}
+
+	publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageDone, BytesDone: written, BytesTotal: bytesTotal, Attempt: attempt})
+	return createResult("success", pdfFilename, written, "")
 }
 
 // setBrowserHeaders 设置完整的浏览器请求头，避免被识别为爬虫
@@ -171,7 +325,9 @@ func setBrowserHeaders(req *http.Request) {
 
 // downloadSinglePDF 下载单个 PDF 文件
 // 注意：Duration 字段由调用者计算（从任务提交到完成的时间）
-func downloadSinglePDF(pageURL string, pdfDir string, client *http.Client, pdfClient *http.Client) DownloadResult {
+// progress/logger 均可为 nil；attempt 是本次调用在 attemptWithMirrorFailover 里的重试序号，
+// 原样带到每个 ProgressEvent 里，供 UI 区分"第几次换镜像重试"。
+func downloadSinglePDF(pageURL string, pdfDir string, client *http.Client, pdfClient *http.Client, solver CaptchaSolver, proxies *ProxyPool, progress chan<- ProgressEvent, logger StructuredLogger, attempt int) DownloadResult {
 	// 辅助函数：创建结果（Duration 由外部计算）
 	createResult := func(status, filename string, size int64, doi, errMsg string) DownloadResult {
 		return DownloadResult{
@@ -212,60 +368,78 @@ func downloadSinglePDF(pageURL string, pdfDir string, client *http.Client, pdfCl
 	delay := time.Duration(500+rand.Intn(1500)) * time.Millisecond // 0.5-2.0 秒
 	time.Sleep(delay)
 
+	publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFetchingPage, Attempt: attempt})
+
 	// 第一步：获取页面 HTML（带重试机制）
 	const maxRetries = 3
 	retryDelay := 2 * time.Second // 初始重试延迟
 
 	var resp *http.Response
 	var req *http.Request
-	for attempt := 0; attempt < maxRetries; attempt++ {
+	for pageAttempt := 0; pageAttempt < maxRetries; pageAttempt++ {
 		req, err = http.NewRequest("GET", pageURL, nil)
 		if err != nil {
 			return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("创建请求失败: %v", err))
 		}
 		setBrowserHeaders(req)
 
-		resp, err = client.Do(req)
+		// 每次重试都换一个出口代理，而不是用同一个 IP 干等，更有机会绕开临时的限流
+		attemptClient, proxy, ok := clientForAttempt(client, proxies)
+		if !ok {
+			return createResult("failed", pdfFilename, 0, doi, "所有代理均不可用")
+		}
+
+		resp, err = attemptClient.Do(req)
 		if err != nil {
-			if attempt < maxRetries-1 {
-				waitTime := retryDelay*time.Duration(attempt+1) + time.Duration(rand.Intn(2000))*time.Millisecond
+			proxies.RecordResult(proxy, false, "conn")
+			if pageAttempt < maxRetries-1 {
+				waitTime := retryDelay*time.Duration(pageAttempt+1) + time.Duration(rand.Intn(2000))*time.Millisecond
 				time.Sleep(waitTime)
 				continue
 			}
+			publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
 			return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("页面请求失败: %v (已重试 %d 次)", err, maxRetries))
 		}
 
-		// 如果是 403 错误，等待后重试
+		// 如果是 403 错误，等待后换代理重试
 		if resp.StatusCode == http.StatusForbidden {
 			resp.Body.Close()
-			if attempt < maxRetries-1 {
-				waitTime := retryDelay*time.Duration(attempt+1) + time.Duration(rand.Intn(2000))*time.Millisecond
+			proxies.RecordResult(proxy, false, "403")
+			if pageAttempt < maxRetries-1 {
+				waitTime := retryDelay*time.Duration(pageAttempt+1) + time.Duration(rand.Intn(2000))*time.Millisecond
 				time.Sleep(waitTime)
 				continue
 			}
+			publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
 			return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("页面请求失败: HTTP 403 (已重试 %d 次)", maxRetries))
 		}
 
 		// 对于 404 错误，如果是第一次尝试，可以重试一次（可能是临时问题）
 		if resp.StatusCode == http.StatusNotFound {
 			resp.Body.Close()
-			if attempt < maxRetries-1 {
-				waitTime := retryDelay*time.Duration(attempt+1) + time.Duration(rand.Intn(2000))*time.Millisecond
+			proxies.RecordResult(proxy, false, "other")
+			if pageAttempt < maxRetries-1 {
+				waitTime := retryDelay*time.Duration(pageAttempt+1) + time.Duration(rand.Intn(2000))*time.Millisecond
 				time.Sleep(waitTime)
 				continue
 			}
+			publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
 			return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("页面请求失败: HTTP 404 (页面不存在，已重试 %d 次)", maxRetries))
 		}
 
 		if resp.StatusCode != http.StatusOK {
 			resp.Body.Close()
+			proxies.RecordResult(proxy, false, "other")
+			publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
 			return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("页面请求失败: HTTP %d", resp.StatusCode))
 		}
+		proxies.RecordResult(proxy, true, "")
 
 		break // 成功，退出重试循环
 	}
 
 	if resp == nil {
+		publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
 		return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("页面请求失败: 已重试 %d 次", maxRetries))
 	}
 	defer resp.Body.Close()
@@ -292,8 +466,19 @@ func downloadSinglePDF(pageURL string, pdfDir string, client *http.Client, pdfCl
 		return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("HTML 解析失败: %v", err))
 	}
 
+	publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageExtracting, Attempt: attempt})
+
 	// 第二步：提取 PDF URL（同时使用 goquery 和原始 HTML）
 	pdfURL := extractPDFURL(doc, string(htmlContent), pageURL)
+
+	if pdfURL == "" && solver != nil {
+		if solved, solvedDoc, solvedHTML := solveCaptchaLoop(doc, pageURL, client, solver); solved {
+			doc = solvedDoc
+			htmlContent = solvedHTML
+			pdfURL = extractPDFURL(doc, string(htmlContent), pageURL)
+		}
+	}
+
 	if pdfURL == "" {
 		// 添加调试信息：检查页面内容
 		htmlStr := string(htmlContent)
@@ -310,7 +495,7 @@ func downloadSinglePDF(pageURL string, pdfDir string, client *http.Client, pdfCl
 			strings.Contains(lowerHtml, "not available through sci-hub") {
 			errorMsg = "文章在 Sci-Hub 上不可用"
 		} else if strings.Contains(lowerHtml, "captcha") {
-			errorMsg += " (检测到验证码)"
+			errorMsg += " (检测到验证码，且求解失败)"
 		} else if strings.Contains(lowerHtml, "not found") || strings.Contains(lowerHtml, "404") {
 			errorMsg += " (页面未找到)"
 		} else if title != "" {
@@ -321,14 +506,17 @@ func downloadSinglePDF(pageURL string, pdfDir string, client *http.Client, pdfCl
 			errorMsg += fmt.Sprintf(" (页面标题: %s)", title)
 		}
 
-		// 保存 HTML 到文件用于调试（仅在失败时）
+		// 保存 HTML 到文件用于调试（仅在失败时），并作为一条结构化日志事件上报，
+		// 而不是像过去那样只是静默落盘、调用方无从得知调试文件已经生成
 		debugDir := filepath.Join(pdfDir, "debug")
 		os.MkdirAll(debugDir, 0755)
 		debugFilename := strings.ReplaceAll(doi, "/", "_")
 		debugFilename = strings.ReplaceAll(debugFilename, ":", "_")
 		debugFile := filepath.Join(debugDir, fmt.Sprintf("%s.html", debugFilename))
 		os.WriteFile(debugFile, htmlContent, 0644)
+		logDebug(logger, "保存调试 HTML", LogFields{"doi": doi, "path": debugFile, "reason": errorMsg})
 
+		publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
 		return createResult("failed", pdfFilename, 0, doi, errorMsg)
 	}
 
@@ -337,9 +525,11 @@ func downloadSinglePDF(pageURL string, pdfDir string, client *http.Client, pdfCl
 	delay = time.Duration(300+rand.Intn(700)) * time.Millisecond // 0.3-1.0 秒
 	time.Sleep(delay)
 
+	publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageDownloading, Attempt: attempt})
+
 	// PDF 下载（带重试机制）
 	var pdfResp *http.Response
-	for attempt := 0; attempt < maxRetries; attempt++ {
+	for pdfAttempt := 0; pdfAttempt < maxRetries; pdfAttempt++ {
 		req, err = http.NewRequest("GET", pdfURL, nil)
 		if err != nil {
 			return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("创建 PDF 请求失败: %v", err))
@@ -348,36 +538,51 @@ func downloadSinglePDF(pageURL string, pdfDir string, client *http.Client, pdfCl
 		// 为 PDF 下载添加 Referer 头
 		req.Header.Set("Referer", pageURL)
 
-		pdfResp, err = pdfClient.Do(req)
+		// 与页面抓取一样，PDF 正文的每次重试也换一个出口代理。注意：若后面命中分块并发下载，
+		// 各分块请求仍直接用 pdfClient（见 downloadRangedPDF），不经过这里选中的代理。
+		attemptClient, proxy, ok := clientForAttempt(pdfClient, proxies)
+		if !ok {
+			return createResult("failed", pdfFilename, 0, doi, "所有代理均不可用")
+		}
+
+		pdfResp, err = attemptClient.Do(req)
 		if err != nil {
-			if attempt < maxRetries-1 {
-				waitTime := retryDelay*time.Duration(attempt+1) + time.Duration(rand.Intn(2000))*time.Millisecond
+			proxies.RecordResult(proxy, false, "conn")
+			if pdfAttempt < maxRetries-1 {
+				waitTime := retryDelay*time.Duration(pdfAttempt+1) + time.Duration(rand.Intn(2000))*time.Millisecond
 				time.Sleep(waitTime)
 				continue
 			}
+			publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
 			return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("PDF 下载失败: %v (已重试 %d 次)", err, maxRetries))
 		}
 
-		// 如果是 403 错误，等待后重试
+		// 如果是 403 错误，等待后换代理重试
 		if pdfResp.StatusCode == http.StatusForbidden {
 			pdfResp.Body.Close()
-			if attempt < maxRetries-1 {
-				waitTime := retryDelay*time.Duration(attempt+1) + time.Duration(rand.Intn(2000))*time.Millisecond
+			proxies.RecordResult(proxy, false, "403")
+			if pdfAttempt < maxRetries-1 {
+				waitTime := retryDelay*time.Duration(pdfAttempt+1) + time.Duration(rand.Intn(2000))*time.Millisecond
 				time.Sleep(waitTime)
 				continue
 			}
+			publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
 			return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("PDF 下载失败: HTTP 403 (已重试 %d 次)", maxRetries))
 		}
 
 		if pdfResp.StatusCode != http.StatusOK {
 			pdfResp.Body.Close()
+			proxies.RecordResult(proxy, false, "other")
+			publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
 			return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("PDF 下载失败: HTTP %d", pdfResp.StatusCode))
 		}
+		proxies.RecordResult(proxy, true, "")
 
 		break // 成功，退出重试循环
 	}
 
 	if pdfResp == nil {
+		publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
 		return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("PDF 下载失败: 已重试 %d 次", maxRetries))
 	}
 	defer pdfResp.Body.Close()
@@ -390,6 +595,58 @@ func downloadSinglePDF(pageURL string, pdfDir string, client *http.Client, pdfCl
 		}
 	}
 
+	// 大文件且服务器声明支持 Range 请求时，改用分块并发下载（可断点续传）
+	if pdfResp.Header.Get("Content-Encoding") != "gzip" &&
+		supportsRangeDownload(pdfResp.Header.Get("Accept-Ranges"), pdfResp.ContentLength) {
+		size := pdfResp.ContentLength
+		pdfResp.Body.Close()
+
+		written, err := downloadRangedPDF(doi, pdfURL, pageURL, pdfFilePath, size, pdfClient, progress)
+		switch {
+		case err == nil:
+			publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageVerifying, BytesDone: written, BytesTotal: size, Attempt: attempt})
+
+			if ok, errMsg := validatePDFHeader(pdfFilePath); !ok {
+				os.Remove(pdfFilePath)
+				publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
+				return createResult("failed", pdfFilename, 0, doi, errMsg)
+			}
+
+			publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageDone, BytesDone: written, BytesTotal: size, Attempt: attempt})
+			return createResult("success", pdfFilename, written, doi, "")
+
+		case errors.Is(err, errRangeNotSupported):
+			// 服务器声明了 Accept-Ranges 但实际没有对 Range 请求返回 206（常见于某些会
+			// 整体转发请求的代理/CDN）。分块下载在这种情况下永远成功不了，但这不代表
+			// DOI 本身不可下载——退回单流下载，和从不支持 Range 的服务器走的是同一条路径。
+			logDebug(logger, "分块下载探测到服务器不支持 Range，回退单流下载", LogFields{"doi": doi, "reason": err.Error()})
+
+			req, err = http.NewRequest("GET", pdfURL, nil)
+			if err != nil {
+				return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("创建 PDF 请求失败: %v", err))
+			}
+			setBrowserHeaders(req)
+			req.Header.Set("Referer", pageURL)
+
+			pdfResp, err = pdfClient.Do(req)
+			if err != nil {
+				publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
+				return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("单流回退下载失败: %v", err))
+			}
+			defer pdfResp.Body.Close()
+
+			if pdfResp.StatusCode != http.StatusOK {
+				publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
+				return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("单流回退下载失败: HTTP %d", pdfResp.StatusCode))
+			}
+			// 继续往下走，复用下面的单流下载逻辑（tmpFile、countingReader、gzip 解压等）
+
+		default:
+			publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
+			return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("分块下载失败: %v", err))
+		}
+	}
+
 	// 创建临时文件
 	tmpFile, err := os.CreateTemp(pdfDir, "*.tmp")
 	if err != nil {
@@ -398,10 +655,15 @@ func downloadSinglePDF(pageURL string, pdfDir string, client *http.Client, pdfCl
 	tmpPath := tmpFile.Name()
 	defer os.Remove(tmpPath)
 
-	// 处理可能的 gzip 压缩
-	var pdfReader io.Reader = pdfResp.Body
+	bytesTotal := pdfResp.ContentLength
+
+	// countingReader 包在最底层的 pdfResp.Body 外面，原因同 downloadDirectPDF：
+	// 即使后面套了一层 gzip 解压，汇报的仍是实际从网络读到的字节数
+	var pdfReader io.Reader = &countingReader{r: pdfResp.Body, onRead: func(done int64) {
+		publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageDownloading, BytesDone: done, BytesTotal: bytesTotal, Attempt: attempt})
+	}}
 	if pdfResp.Header.Get("Content-Encoding") == "gzip" {
-		gzReader, err := gzip.NewReader(pdfResp.Body)
+		gzReader, err := gzip.NewReader(pdfReader)
 		if err != nil {
 			return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("PDF 解压缩失败: %v", err))
 		}
@@ -421,50 +683,62 @@ func downloadSinglePDF(pageURL string, pdfDir string, client *http.Client, pdfCl
 		return createResult("failed", pdfFilename, 0, doi, "下载的文件大小为 0")
 	}
 
+	publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageVerifying, BytesDone: written, BytesTotal: bytesTotal, Attempt: attempt})
+
 	// 验证文件是否为有效的 PDF（检查文件头）
-	file, err := os.Open(tmpPath)
+	if ok, errMsg := validatePDFHeader(tmpPath); !ok {
+		publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageFailed, Attempt: attempt})
+		return createResult("failed", pdfFilename, 0, doi, errMsg)
+	}
+
+	// 移动到最终位置
+	if err := os.Rename(tmpPath, pdfFilePath); err != nil {
+		return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("移动文件失败: %v", err))
+	}
+
+	publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageDone, BytesDone: written, BytesTotal: bytesTotal, Attempt: attempt})
+	return createResult("success", pdfFilename, written, doi, "")
+}
+
+// validatePDFHeader 检查文件是否以 %PDF 开头；若不是，尝试从内容判断失败原因
+// （HTML 错误页、验证码页面、gzip 压缩等），返回人类可读的错误信息
+func validatePDFHeader(path string) (bool, string) {
+	file, err := os.Open(path)
 	if err != nil {
-		return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("打开文件失败: %v", err))
+		return false, fmt.Sprintf("打开文件失败: %v", err)
 	}
 	defer file.Close()
 
 	header := make([]byte, 4)
 	if _, err := file.Read(header); err != nil {
-		return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("读取文件头失败: %v", err))
+		return false, fmt.Sprintf("读取文件头失败: %v", err)
 	}
 
-	if string(header) != "%PDF" {
-		// 检查是否是 HTML 错误页面
-		file.Seek(0, 0)
-		contentStart := make([]byte, 512)
-		file.Read(contentStart)
-		contentStr := strings.ToLower(string(contentStart))
-
-		errorMsg := "下载的文件不是有效的 PDF 文件"
-		if strings.Contains(contentStr, "<html") || strings.Contains(contentStr, "<!doctype") {
-			// 尝试提取错误信息
-			if strings.Contains(contentStr, "403") || strings.Contains(contentStr, "forbidden") {
-				errorMsg += " (收到 HTML 403 错误页面)"
-			} else if strings.Contains(contentStr, "404") || strings.Contains(contentStr, "not found") {
-				errorMsg += " (收到 HTML 404 错误页面)"
-			} else if strings.Contains(contentStr, "captcha") {
-				errorMsg += " (收到验证码页面)"
-			} else {
-				errorMsg += " (收到 HTML 错误页面而非 PDF)"
-			}
-		} else if len(header) > 0 && header[0] == 0x1f && header[1] == 0x8b {
-			errorMsg += " (文件是 gzip 压缩格式，可能是 HTML 页面)"
-		}
-
-		return createResult("failed", pdfFilename, 0, doi, errorMsg)
+	if string(header) == "%PDF" {
+		return true, ""
 	}
 
-	// 移动到最终位置
-	if err := os.Rename(tmpPath, pdfFilePath); err != nil {
-		return createResult("failed", pdfFilename, 0, doi, fmt.Sprintf("移动文件失败: %v", err))
+	file.Seek(0, 0)
+	contentStart := make([]byte, 512)
+	file.Read(contentStart)
+	contentStr := strings.ToLower(string(contentStart))
+
+	errorMsg := "下载的文件不是有效的 PDF 文件"
+	if strings.Contains(contentStr, "<html") || strings.Contains(contentStr, "<!doctype") {
+		if strings.Contains(contentStr, "403") || strings.Contains(contentStr, "forbidden") {
+			errorMsg += " (收到 HTML 403 错误页面)"
+		} else if strings.Contains(contentStr, "404") || strings.Contains(contentStr, "not found") {
+			errorMsg += " (收到 HTML 404 错误页面)"
+		} else if strings.Contains(contentStr, "captcha") {
+			errorMsg += " (收到验证码页面)"
+		} else {
+			errorMsg += " (收到 HTML 错误页面而非 PDF)"
+		}
+	} else if len(header) > 0 && header[0] == 0x1f && header[1] == 0x8b {
+		errorMsg += " (文件是 gzip 压缩格式，可能是 HTML 页面)"
 	}
 
-	return createResult("success", pdfFilename, written, doi, "")
+	return false, errorMsg
 }
 
 // extractPDFURL 从 HTML 中提取 PDF URL
@@ -624,3 +898,151 @@ func resolveURL(base *url.URL, ref string) string {
 	resolved := base.ResolveReference(refURL)
 	return resolved.String()
 }
+
+// detectCaptchaChallenge 检测页面上是否存在 Sci-Hub 风格的验证码表单
+//（<img id="captcha"> 搭配一个包含文本输入框的 <form>），若存在则提取提交所需的信息
+func detectCaptchaChallenge(doc *goquery.Document, pageURL string) (*CaptchaChallenge, bool) {
+	img := doc.Find("img#captcha").First()
+	if img.Length() == 0 {
+		return nil, false
+	}
+
+	src, exists := img.Attr("src")
+	if !exists || src == "" {
+		return nil, false
+	}
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, false
+	}
+	imgURL := resolveURL(base, src)
+	if imgURL == "" {
+		return nil, false
+	}
+
+	form := img.Closest("form")
+	if form.Length() == 0 {
+		return nil, false
+	}
+
+	action, _ := form.Attr("action")
+	formAction := resolveURL(base, action)
+	if formAction == "" {
+		formAction = pageURL
+	}
+
+	method, _ := form.Attr("method")
+	if method == "" {
+		method = "POST"
+	}
+
+	extraFields := make(map[string]string)
+	var answerField string
+	form.Find("input").Each(func(i int, s *goquery.Selection) {
+		name, ok := s.Attr("name")
+		if !ok || name == "" {
+			return
+		}
+		inputType, _ := s.Attr("type")
+		switch strings.ToLower(inputType) {
+		case "hidden":
+			value, _ := s.Attr("value")
+			extraFields[name] = value
+		case "submit", "button":
+			// 忽略
+		default:
+			if answerField == "" {
+				answerField = name
+			}
+		}
+	})
+	if answerField == "" {
+		return nil, false
+	}
+
+	return &CaptchaChallenge{
+		FormAction:  formAction,
+		FormMethod:  strings.ToUpper(method),
+		AnswerField: answerField,
+		ExtraFields: extraFields,
+	}, true
+}
+
+// solveCaptchaLoop 在检测到验证码表单时反复尝试求解并重新提交，直到页面不再出现验证码表单、
+// solver 报错，或达到 maxCaptchaAttempts 次尝试。client 必须带有 cookie jar，
+// 这样表单提交时的会话状态才能与上一次页面请求保持一致。
+func solveCaptchaLoop(doc *goquery.Document, pageURL string, client *http.Client, solver CaptchaSolver) (bool, *goquery.Document, []byte) {
+	currentDoc := doc
+
+	for attempt := 0; attempt < maxCaptchaAttempts; attempt++ {
+		challenge, ok := detectCaptchaChallenge(currentDoc, pageURL)
+		if !ok {
+			return false, currentDoc, nil
+		}
+
+		base, err := url.Parse(pageURL)
+		if err != nil {
+			return false, currentDoc, nil
+		}
+
+		imgSrc, _ := currentDoc.Find("img#captcha").First().Attr("src")
+		resolvedImgURL := resolveURL(base, imgSrc)
+		if resolvedImgURL == "" {
+			return false, currentDoc, nil
+		}
+
+		imgResp, err := client.Get(resolvedImgURL)
+		if err != nil {
+			return false, currentDoc, nil
+		}
+		imgBytes, err := io.ReadAll(imgResp.Body)
+		imgResp.Body.Close()
+		if err != nil {
+			return false, currentDoc, nil
+		}
+		challenge.ImageBytes = imgBytes
+
+		answer, err := solver.Solve(*challenge)
+		if err != nil {
+			return false, currentDoc, nil
+		}
+
+		form := url.Values{}
+		for k, v := range challenge.ExtraFields {
+			form.Set(k, v)
+		}
+		form.Set(challenge.AnswerField, answer)
+
+		req, err := http.NewRequest(challenge.FormMethod, challenge.FormAction, strings.NewReader(form.Encode()))
+		if err != nil {
+			return false, currentDoc, nil
+		}
+		setBrowserHeaders(req)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Referer", pageURL)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return false, currentDoc, nil
+		}
+		respHTML, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return false, currentDoc, nil
+		}
+
+		newDoc, err := goquery.NewDocumentFromReader(strings.NewReader(string(respHTML)))
+		if err != nil {
+			return false, currentDoc, nil
+		}
+		currentDoc = newDoc
+
+		if _, stillCaptcha := detectCaptchaChallenge(currentDoc, pageURL); !stillCaptcha {
+			return true, currentDoc, respHTML
+		}
+		// 验证码答错，进入下一次尝试
+	}
+
+	return false, currentDoc, nil
+}
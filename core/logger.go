@@ -102,7 +102,10 @@ func (l *Logger) SaveDOIsOnly(errors []DownloadError) error {
 	return nil
 }
 
-// SaveDownloadLog 保存完整的下载日志
+// SaveDownloadLog 保存完整的下载日志摘要。stats 通常来自正常结束的一次 RunPipeline 调用；
+// 若本次运行使用了 RotatingLogger，即使 stats 为 nil（例如从一次被杀死的进程恢复），
+// 也可以先用 ReconstructStatsFromShards 从 JSONL 分片重建出 stats 再传入本函数
+// （重建的 stats 不含总大小与镜像统计，这两部分不会写入日志）。
 func (l *Logger) SaveDownloadLog(stats *DownloadStats) error {
 	filename := filepath.Join(l.LogDir, fmt.Sprintf("download_log_%s.txt", l.Timestamp))
 	file, err := os.Create(filename)
@@ -175,6 +178,17 @@ func (l *Logger) SaveDownloadLog(stats *DownloadStats) error {
 		}
 	}
 
+	// 写入镜像健康统计
+	if len(stats.Mirrors) > 0 {
+		fmt.Fprintf(file, "\n=== 镜像统计 ===\n\n")
+		for _, m := range stats.Mirrors {
+			fmt.Fprintf(file, "--- %s ---\n", m.BaseURL)
+			fmt.Fprintf(file, "  请求数: %d, 成功数: %d, 成功率: %.2f%%\n", m.Requests, m.Success, m.SuccessRate)
+			fmt.Fprintf(file, "  平均延迟: %.1fms\n", m.AvgLatencyMs)
+			fmt.Fprintf(file, "  隔离次数: %d, 当前状态: %s\n", m.QuarantineEvents, quarantineLabel(m.Quarantined))
+		}
+	}
+
 	fmt.Fprintf(file, "\n========================================\n")
 	fmt.Fprintf(file, "日志结束\n")
 	fmt.Fprintf(file, "========================================\n")
@@ -182,6 +196,14 @@ func (l *Logger) SaveDownloadLog(stats *DownloadStats) error {
 	return nil
 }
 
+// quarantineLabel 将镜像当前是否处于隔离状态转换为可读的中文标签
+func quarantineLabel(quarantined bool) string {
+	if quarantined {
+		return "隔离中"
+	}
+	return "健康"
+}
+
 // GetLogFilePaths 获取日志文件路径
 func (l *Logger) GetLogFilePaths() (logFile, failedFile, retryFile string) {
 	logFile = filepath.Join(l.LogDir, fmt.Sprintf("download_log_%s.txt", l.Timestamp))
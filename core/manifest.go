@@ -0,0 +1,204 @@
+// core/manifest.go
+
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	manifestDirName  = ".doihive"
+	manifestFileName = "manifest.json"
+)
+
+// ManifestEntry 记录某个 DOI 最近一次下载的终态
+type ManifestEntry struct {
+	DOI          string    `json:"doi"`
+	Status       string    `json:"status"` // success, skip, failed
+	Filename     string    `json:"filename,omitempty"`
+	Size         int64     `json:"size,omitempty"`
+	SHA256       string    `json:"sha256,omitempty"`
+	SourceMirror string    `json:"source_mirror,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+	HTTPStatus   int       `json:"http_status,omitempty"`
+	Attempts     int       `json:"attempts"`
+}
+
+// Manifest 是跨运行持久化的下载清单，用于断点续传与完整性校验
+type Manifest struct {
+	path string
+
+	mu      sync.Mutex
+	Entries map[string]ManifestEntry `json:"entries"`
+}
+
+// NewManifest 加载 pdfDir/.doihive/manifest.json，不存在时返回一个空清单
+func NewManifest(pdfDir string) (*Manifest, error) {
+	dir := filepath.Join(pdfDir, manifestDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("无法创建清单目录: %v", err)
+	}
+
+	m := &Manifest{
+		path:    filepath.Join(dir, manifestFileName),
+		Entries: make(map[string]ManifestEntry),
+	}
+
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, fmt.Errorf("无法读取清单文件: %v", err)
+	}
+
+	if len(data) == 0 {
+		return m, nil
+	}
+
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("清单文件解析失败: %v", err)
+	}
+	if m.Entries == nil {
+		m.Entries = make(map[string]ManifestEntry)
+	}
+
+	return m, nil
+}
+
+// save 原子地将清单写回磁盘：先写临时文件，再 rename 覆盖（调用方需持有 m.mu）
+func (m *Manifest) save() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("清单序列化失败: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(m.path), "manifest-*.tmp")
+	if err != nil {
+		return fmt.Errorf("无法创建临时清单文件: %v", err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("写入临时清单文件失败: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("关闭临时清单文件失败: %v", err)
+	}
+
+	if err := os.Rename(tmpPath, m.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("替换清单文件失败: %v", err)
+	}
+
+	return nil
+}
+
+// Get 返回给定 DOI 的清单记录
+func (m *Manifest) Get(doi string) (ManifestEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.Entries[doi]
+	return entry, ok
+}
+
+// ShouldSkip 判断某个 DOI 是否可以跳过本次下载：清单中标记为 success，
+// 对应文件仍然存在，且其 SHA-256 仍与清单记录一致。
+func (m *Manifest) ShouldSkip(doi, pdfDir string) bool {
+	entry, ok := m.Get(doi)
+	if !ok || entry.Status != "success" {
+		return false
+	}
+
+	pdfPath := filepath.Join(pdfDir, entry.Filename)
+	sum, err := sha256File(pdfPath)
+	if err != nil {
+		return false
+	}
+
+	return sum == entry.SHA256
+}
+
+// Record 记录一个 DOI 的终态结果，并立即原子落盘，确保进程崩溃也不丢最后一次结果。
+func (m *Manifest) Record(entry ManifestEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if prev, ok := m.Entries[entry.DOI]; ok {
+		entry.Attempts = prev.Attempts + 1
+	} else {
+		entry.Attempts = 1
+	}
+	entry.Timestamp = time.Now()
+	m.Entries[entry.DOI] = entry
+
+	return m.save()
+}
+
+// VerifyResult 描述 --verify 模式下单个文件的校验结果
+type VerifyResult struct {
+	DOI    string
+	OK     bool
+	Reason string
+}
+
+// VerifyAll 走查清单中的每一条 success 记录，重新计算 SHA-256，
+// 并将被篡改或截断的文件标记出来（不会自动从清单中删除，调用方可据此重新排入重试队列）。
+func (m *Manifest) VerifyAll(pdfDir string) ([]VerifyResult, error) {
+	m.mu.Lock()
+	entries := make([]ManifestEntry, 0, len(m.Entries))
+	for _, e := range m.Entries {
+		entries = append(entries, e)
+	}
+	m.mu.Unlock()
+
+	results := make([]VerifyResult, 0, len(entries))
+	for _, e := range entries {
+		if e.Status != "success" {
+			continue
+		}
+
+		pdfPath := filepath.Join(pdfDir, e.Filename)
+		sum, err := sha256File(pdfPath)
+		if err != nil {
+			results = append(results, VerifyResult{DOI: e.DOI, OK: false, Reason: fmt.Sprintf("文件缺失或不可读: %v", err)})
+			continue
+		}
+
+		if sum != e.SHA256 {
+			results = append(results, VerifyResult{DOI: e.DOI, OK: false, Reason: "SHA-256 不匹配，文件可能被篡改或截断"})
+			continue
+		}
+
+		results = append(results, VerifyResult{DOI: e.DOI, OK: true})
+	}
+
+	return results, nil
+}
+
+// sha256File 计算文件内容的 SHA-256（十六进制字符串）
+func sha256File(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,375 @@
+// core/mirror.go
+
+package core
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认的镜像池参数
+const (
+	defaultQuarantineWindow   = 2 * time.Minute // 统计 403/验证码失败次数的滑动窗口
+	defaultQuarantineDuration = 5 * time.Minute // 被隔离镜像的冷却时长
+	defaultFailureThreshold   = 3               // 窗口内累计多少次 403/验证码触发隔离
+	ewmaAlpha                 = 0.3             // 延迟 EWMA 平滑系数
+
+	// bestWaitTimeout 是 Best 在所有镜像都不可用时愿意阻塞等待的兜底时长，与
+	// quarantineDuration 无关：quarantineDuration 默认长达 5 分钟，如果把兜底期限
+	// 定成"quarantineDuration 加一点余量"，Best 实质上就和不限时阻塞没有区别。
+	// 这里选一个远小于默认冷却时长的固定值，让调用方（resolveAndDownload）能在可
+	// 接受的时间内改走下一个 resolver 或放弃该 DOI，而不是替这个 DOI 把整个冷却期都耗完。
+	bestWaitTimeout = 10 * time.Second
+)
+
+// MirrorStats 记录单个镜像的健康状况
+type MirrorStats struct {
+	Requests            int
+	Success             int
+	ConsecutiveFailures int
+	AvgLatencyMs        float64
+	CooldownUntil       time.Time
+	QuarantineEvents    int
+	recentFailureAt     []time.Time
+	unavailableDOIs     map[string]struct{}
+}
+
+// Mirror 是镜像池中的一个 Sci-Hub 镜像站点
+type Mirror struct {
+	BaseURL string
+
+	mu    sync.Mutex
+	stats MirrorStats
+}
+
+// MirrorStatSnapshot 是某个镜像健康状况的只读快照，用于写入下载日志
+type MirrorStatSnapshot struct {
+	BaseURL          string
+	Requests         int
+	Success          int
+	SuccessRate      float64
+	AvgLatencyMs     float64
+	QuarantineEvents int
+	Quarantined      bool
+}
+
+// MirrorPool 管理一组 Sci-Hub 镜像，按健康分数挑选最优镜像
+type MirrorPool struct {
+	mu                 sync.Mutex
+	cond               *sync.Cond
+	mirrors            []*Mirror
+	quarantineWindow   time.Duration
+	quarantineDuration time.Duration
+	failureThreshold   int
+}
+
+// NewMirrorPool 基于给定的 base URL 列表创建镜像池
+func NewMirrorPool(baseURLs []string) (*MirrorPool, error) {
+	if len(baseURLs) == 0 {
+		return nil, fmt.Errorf("镜像列表不能为空")
+	}
+
+	p := &MirrorPool{
+		mirrors:            make([]*Mirror, 0, len(baseURLs)),
+		quarantineWindow:   defaultQuarantineWindow,
+		quarantineDuration: defaultQuarantineDuration,
+		failureThreshold:   defaultFailureThreshold,
+	}
+	p.cond = sync.NewCond(&p.mu)
+
+	for _, u := range baseURLs {
+		u = strings.TrimRight(strings.TrimSpace(u), "/")
+		if u == "" {
+			continue
+		}
+		p.mirrors = append(p.mirrors, &Mirror{
+			BaseURL: u,
+			stats: MirrorStats{
+				unavailableDOIs: make(map[string]struct{}),
+			},
+		})
+	}
+
+	if len(p.mirrors) == 0 {
+		return nil, fmt.Errorf("镜像列表不能为空")
+	}
+
+	return p, nil
+}
+
+// LoadMirrorsFromFile 从一个纯文本配置文件加载镜像列表（每行一个带 scheme 的镜像 base URL，# 开头为注释）
+func LoadMirrorsFromFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开镜像配置文件: %v", err)
+	}
+	defer file.Close()
+
+	mirrors := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		mirrors = append(mirrors, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取镜像配置文件失败: %v", err)
+	}
+
+	return mirrors, nil
+}
+
+// isQuarantined 判断该镜像当前是否处于冷却中（调用方需持有 m.mu）
+func (m *Mirror) isQuarantined(now time.Time) bool {
+	return now.Before(m.stats.CooldownUntil)
+}
+
+// score 计算镜像的健康分数，分数越高越优先（调用方需持有 m.mu）
+func (m *Mirror) score() float64 {
+	if m.stats.Requests == 0 {
+		return 1000 // 从未使用过的镜像优先尝试一次
+	}
+	successRate := float64(m.stats.Success) / float64(m.stats.Requests)
+	latencyPenalty := m.stats.AvgLatencyMs
+	if latencyPenalty <= 0 {
+		latencyPenalty = 1
+	}
+	return successRate*1000 - latencyPenalty/100 - float64(m.stats.ConsecutiveFailures)*50
+}
+
+// selectBest 为给定 DOI 挑选当前最优的镜像，没有可用镜像时返回 nil（调用方需持有 p.mu）
+func (p *MirrorPool) selectBest(doi string) *Mirror {
+	var best *Mirror
+	var bestScore float64
+	now := time.Now()
+
+	for _, m := range p.mirrors {
+		m.mu.Lock()
+		quarantined := m.isQuarantined(now)
+		_, unavailable := m.stats.unavailableDOIs[doi]
+		s := m.score()
+		m.mu.Unlock()
+
+		if quarantined || unavailable {
+			continue
+		}
+		if best == nil || s > bestScore {
+			best = m
+			bestScore = s
+		}
+	}
+
+	return best
+}
+
+// Best 为给定 DOI 挑选当前最优的镜像；若该 DOI 在某镜像上被标记为不可用则跳过该镜像。
+// 当所有镜像都处于冷却状态时，阻塞等待直到有镜像恢复可用（由 RecordResult 唤醒），
+// 但绝不会无限期阻塞，也不会傻等完整个冷却期：等待按固定的 bestWaitTimeout 限时
+// （期间用 time.AfterFunc 定时广播，弥补冷却到期本身不会触发 Broadcast 的问题），
+// 超过这个兜底期限后返回 nil，让调用方（resolveAndDownload）改走下一个 resolver
+// 或放弃该 DOI，而不是让 worker 卡死到 quarantineDuration 那么长。
+func (p *MirrorPool) Best(doi string) *Mirror {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	deadline := time.Now().Add(bestWaitTimeout)
+	for {
+		if best := p.selectBest(doi); best != nil {
+			return best
+		}
+		if !time.Now().Before(deadline) {
+			return nil
+		}
+
+		wait := p.nearestWakeLocked()
+		if remaining := time.Until(deadline); remaining < wait {
+			wait = remaining
+		}
+		timer := time.AfterFunc(wait, func() {
+			p.mu.Lock()
+			p.cond.Broadcast()
+			p.mu.Unlock()
+		})
+		p.cond.Wait()
+		timer.Stop()
+	}
+}
+
+// nearestWakeLocked 返回距离池中最近一次冷却到期还需要等待多久（调用方需持有 p.mu），
+// 没有任何镜像处于冷却中时退化为一个较短的轮询间隔，避免死等。
+func (p *MirrorPool) nearestWakeLocked() time.Duration {
+	now := time.Now()
+	nearest := 200 * time.Millisecond
+	found := false
+
+	for _, m := range p.mirrors {
+		m.mu.Lock()
+		cooldownUntil := m.stats.CooldownUntil
+		m.mu.Unlock()
+
+		if cooldownUntil.After(now) {
+			if d := cooldownUntil.Sub(now); !found || d < nearest {
+				nearest = d
+				found = true
+			}
+		}
+	}
+
+	return nearest
+}
+
+// BestNonBlocking 与 Best 类似，但在没有可用镜像时立即返回 nil 而不是阻塞等待。
+// 供同一个 DOI 在单次下载尝试中做镜像间故障转移时使用：调用方已经拿到过一个镜像，
+// 失败后想尝试下一个，此时不应该像首次选择那样排队等待某个镜像解除冷却。
+func (p *MirrorPool) BestNonBlocking(doi string) *Mirror {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.selectBest(doi)
+}
+
+// Len 返回镜像池中镜像的数量
+func (p *MirrorPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.mirrors)
+}
+
+// RecordResult 记录一次请求的结果，更新镜像的健康统计，并在必要时触发隔离。
+// 返回值表示本次调用是否恰好触发了隔离（供上层向 EventBus 发布 MirrorQuarantined）。
+func (p *MirrorPool) RecordResult(m *Mirror, success bool, latency time.Duration, reason string) bool {
+	justQuarantined := false
+	m.mu.Lock()
+	m.stats.Requests++
+	if m.stats.AvgLatencyMs == 0 {
+		m.stats.AvgLatencyMs = float64(latency.Milliseconds())
+	} else {
+		m.stats.AvgLatencyMs = ewmaAlpha*float64(latency.Milliseconds()) + (1-ewmaAlpha)*m.stats.AvgLatencyMs
+	}
+
+	if success {
+		m.stats.Success++
+		m.stats.ConsecutiveFailures = 0
+	} else {
+		m.stats.ConsecutiveFailures++
+
+		if reason == "403" || reason == "captcha" {
+			now := time.Now()
+			m.stats.recentFailureAt = append(m.stats.recentFailureAt, now)
+
+			// 只保留窗口内的失败记录
+			cutoff := now.Add(-p.quarantineWindow)
+			kept := m.stats.recentFailureAt[:0]
+			for _, t := range m.stats.recentFailureAt {
+				if t.After(cutoff) {
+					kept = append(kept, t)
+				}
+			}
+			m.stats.recentFailureAt = kept
+
+			if len(m.stats.recentFailureAt) >= p.failureThreshold {
+				m.stats.CooldownUntil = now.Add(p.quarantineDuration)
+				m.stats.QuarantineEvents++
+				m.stats.recentFailureAt = nil
+				justQuarantined = true
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	// 唤醒所有可能在等待镜像恢复的 worker
+	p.cond.Broadcast()
+
+	return justQuarantined
+}
+
+// MarkUnavailable 标记某个 DOI 在该镜像上返回了"文章不可用"，避免在该镜像上重复重试
+func (p *MirrorPool) MarkUnavailable(m *Mirror, doi string) {
+	m.mu.Lock()
+	m.stats.unavailableDOIs[doi] = struct{}{}
+	m.mu.Unlock()
+}
+
+// ProbeMirrors 对池中每个镜像发起一次轻量级 HEAD 探测（不会回退为 GET：任何低于 500 的
+// 状态码，包括不支持 HEAD 时的 405，都算作可达），记录延迟与是否可达，供启动时预热健康分数——这样第一批真正的下载任务就不会
+// 把"从未用过"的高优先级分配给实际上已经失效的镜像。
+func (p *MirrorPool) ProbeMirrors(client *http.Client, timeout time.Duration) {
+	p.mu.Lock()
+	mirrors := make([]*Mirror, len(p.mirrors))
+	copy(mirrors, p.mirrors)
+	p.mu.Unlock()
+
+	probeClient := client
+	if probeClient == nil {
+		probeClient = &http.Client{}
+	}
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	var wg sync.WaitGroup
+	for _, m := range mirrors {
+		wg.Add(1)
+		go func(m *Mirror) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			req, err := http.NewRequestWithContext(ctx, "HEAD", m.BaseURL, nil)
+			if err != nil {
+				p.RecordResult(m, false, timeout, "other")
+				return
+			}
+			setBrowserHeaders(req)
+
+			start := time.Now()
+			resp, err := probeClient.Do(req)
+			latency := time.Since(start)
+
+			if err != nil || (resp != nil && resp.StatusCode >= 500) {
+				p.RecordResult(m, false, latency, "other")
+				return
+			}
+			resp.Body.Close()
+
+			p.RecordResult(m, true, latency, "")
+		}(m)
+	}
+	wg.Wait()
+}
+
+// Snapshot 返回所有镜像当前健康状况的只读快照，供日志记录使用
+func (p *MirrorPool) Snapshot() []MirrorStatSnapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	snapshots := make([]MirrorStatSnapshot, 0, len(p.mirrors))
+	for _, m := range p.mirrors {
+		m.mu.Lock()
+		var successRate float64
+		if m.stats.Requests > 0 {
+			successRate = float64(m.stats.Success) / float64(m.stats.Requests) * 100
+		}
+		snapshots = append(snapshots, MirrorStatSnapshot{
+			BaseURL:          m.BaseURL,
+			Requests:         m.stats.Requests,
+			Success:          m.stats.Success,
+			SuccessRate:      successRate,
+			AvgLatencyMs:     m.stats.AvgLatencyMs,
+			QuarantineEvents: m.stats.QuarantineEvents,
+			Quarantined:      m.isQuarantined(now),
+		})
+		m.mu.Unlock()
+	}
+
+	return snapshots
+}
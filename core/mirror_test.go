@@ -0,0 +1,83 @@
+// core/mirror_test.go
+
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMirrorScorePrefersUntriedThenHealthierMirrors(t *testing.T) {
+	fresh := &Mirror{BaseURL: "https://fresh.example"}
+	if got := fresh.score(); got != 1000 {
+		t.Fatalf("score() of untried mirror = %v, want 1000", got)
+	}
+
+	healthy := &Mirror{BaseURL: "https://healthy.example", stats: MirrorStats{
+		Requests: 10, Success: 10, AvgLatencyMs: 200,
+	}}
+	flaky := &Mirror{BaseURL: "https://flaky.example", stats: MirrorStats{
+		Requests: 10, Success: 4, AvgLatencyMs: 200, ConsecutiveFailures: 3,
+	}}
+
+	if healthy.score() <= flaky.score() {
+		t.Fatalf("healthy.score() = %v, flaky.score() = %v; want healthy to score higher", healthy.score(), flaky.score())
+	}
+}
+
+func TestMirrorPoolSelectBestSkipsQuarantinedAndUnavailable(t *testing.T) {
+	pool, err := NewMirrorPool([]string{"https://a.example", "https://b.example"})
+	if err != nil {
+		t.Fatalf("NewMirrorPool() error = %v", err)
+	}
+
+	const doi = "10.1000/select-best-test"
+
+	a := pool.mirrors[0]
+	b := pool.mirrors[1]
+
+	// 把 a 打到隔离阈值，迫使 selectBest 转而选择 b
+	for i := 0; i < defaultFailureThreshold; i++ {
+		pool.RecordResult(a, false, 10*time.Millisecond, "403")
+	}
+
+	best := pool.BestNonBlocking(doi)
+	if best != b {
+		t.Fatalf("BestNonBlocking() = %v, want the non-quarantined mirror %v", best.BaseURL, b.BaseURL)
+	}
+
+	// 把 b 也标记为该 DOI 不可用，此时应该没有可用镜像
+	pool.MarkUnavailable(b, doi)
+	if got := pool.BestNonBlocking(doi); got != nil {
+		t.Fatalf("BestNonBlocking() = %v, want nil when every mirror is quarantined or unavailable", got)
+	}
+}
+
+func TestMirrorPoolBestReturnsNilAfterDeadlineInsteadOfBlockingForever(t *testing.T) {
+	pool, err := NewMirrorPool([]string{"https://only.example"})
+	if err != nil {
+		t.Fatalf("NewMirrorPool() error = %v", err)
+	}
+
+	const doi = "10.1000/deadlock-regression"
+
+	// 标记成该 DOI 在唯一的镜像上永久不可用（不像隔离那样会在冷却期后自动清除），
+	// 这样 selectBest 永远找不到候选，Best() 必须靠 bestWaitTimeout 兜底才能返回，
+	// 而不是像隔离场景那样在冷却到期后"意外"选到这个镜像，让断言变得不可靠。
+	only := pool.mirrors[0]
+	pool.MarkUnavailable(only, doi)
+
+	done := make(chan *Mirror, 1)
+	go func() {
+		done <- pool.Best(doi)
+	}()
+
+	select {
+	case m := <-done:
+		if m != nil {
+			t.Fatalf("Best() = %v, want nil once the only mirror is permanently unavailable for this DOI", m.BaseURL)
+		}
+	case <-time.After(bestWaitTimeout + 5*time.Second):
+		t.Fatal("Best() blocked forever instead of returning nil after its deadline")
+	}
+}
@@ -0,0 +1,459 @@
+// core/parser.go
+
+package core
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// doiPattern 用于从任意文本片段中提取形如 10.xxxx/yyyy 的 DOI
+var doiPattern = regexp.MustCompile(`(?i)10\.\d{4,9}/[^\s"'{}<>]+`)
+
+// ParseOptions 为需要额外配置的解析器（目前只有 CSV）传入参数
+type ParseOptions struct {
+	DOIColumn string // CSV 解析器使用：DOI 所在列名，大小写不敏感，默认 "doi"
+}
+
+// Record 是某种书目格式中的一条记录，Lines 保留原始内容供缺失详情展示使用
+type Record struct {
+	Lines []string
+	DOI   string // 已校验通过的 DOI，缺失时为空字符串
+}
+
+// RecordParser 把某种书目格式的原始内容解析为一组 Record
+type RecordParser interface {
+	Name() string
+	Extensions() []string
+	Sniff(content string) bool
+	ParseRecords(content string, opts ParseOptions) []Record
+}
+
+// parserRegistry 列出所有内置解析器，detectParser 按扩展名 + 内容嗅探从中选择
+var parserRegistry = []RecordParser{
+	&wosParser{},
+	&risParser{},
+	&bibtexParser{},
+	&endnoteXMLParser{},
+	&cslJSONParser{},
+	&csvParser{},
+	&zoteroSQLiteParser{},
+}
+
+// registeredExtensions 返回所有内置解析器声明的扩展名，用于 getInputFiles 的默认白名单
+func registeredExtensions() []string {
+	exts := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, p := range parserRegistry {
+		for _, e := range p.Extensions() {
+			if !seen[e] {
+				seen[e] = true
+				exts = append(exts, e)
+			}
+		}
+	}
+	return exts
+}
+
+// detectParser 根据文件扩展名筛选候选解析器，再用内容嗅探消歧；
+// 扩展名未知时退化为对所有解析器进行内容嗅探。
+func detectParser(filePath, content string) RecordParser {
+	ext := strings.ToLower(filepath.Ext(filePath))
+
+	candidates := make([]RecordParser, 0)
+	for _, p := range parserRegistry {
+		for _, e := range p.Extensions() {
+			if e == ext {
+				candidates = append(candidates, p)
+			}
+		}
+	}
+
+	for _, p := range candidates {
+		if p.Sniff(content) {
+			return p
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0]
+	}
+
+	for _, p := range parserRegistry {
+		if p.Sniff(content) {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// validDOI 清理首尾标点并校验候选字符串是否形如合法 DOI，不合法时返回空字符串
+func validDOI(candidate string) string {
+	candidate = strings.TrimSpace(candidate)
+	candidate = strings.Trim(candidate, `{}"',;`)
+	candidate = strings.TrimRight(candidate, ".")
+	if doiFullPattern.MatchString(candidate) {
+		return candidate
+	}
+	return ""
+}
+
+var doiFullPattern = regexp.MustCompile(`^10\.\d{4,9}/[^\s]+$`)
+
+// ---- WoS 纯文本（现有格式） ----
+
+type wosParser struct{}
+
+func (p *wosParser) Name() string          { return "wos-txt" }
+func (p *wosParser) Extensions() []string  { return []string{".txt"} }
+func (p *wosParser) Sniff(content string) bool {
+	return strings.Contains(content, "\nER\n") || strings.HasPrefix(strings.TrimSpace(content), "FN ")
+}
+
+func (p *wosParser) ParseRecords(content string, opts ParseOptions) []Record {
+	blocks := strings.Split(content, "\nER\n")
+	records := make([]Record, 0, len(blocks))
+
+	for _, block := range blocks {
+		block = strings.TrimSpace(block)
+		if block == "" || block == "EF" || (strings.HasPrefix(block, "EF") && len(strings.Fields(block)) == 1) {
+			continue
+		}
+
+		lines := strings.Split(block, "\n")
+		lines = append(lines, "ER")
+
+		doi := ""
+		for _, line := range lines {
+			if strings.HasPrefix(line, "DI") {
+				parts := strings.Fields(line)
+				if len(parts) >= 2 {
+					doi = validDOI(strings.Join(parts[1:], " "))
+				}
+				break
+			}
+		}
+
+		records = append(records, Record{Lines: lines, DOI: doi})
+	}
+
+	return records
+}
+
+// ---- RIS ----
+
+type risParser struct{}
+
+func (p *risParser) Name() string         { return "ris" }
+func (p *risParser) Extensions() []string { return []string{".ris"} }
+func (p *risParser) Sniff(content string) bool {
+	return strings.Contains(content, "\nTY  -") || strings.HasPrefix(strings.TrimSpace(content), "TY  -")
+}
+
+func (p *risParser) ParseRecords(content string, opts ParseOptions) []Record {
+	blocks := strings.Split(content, "ER  -")
+	records := make([]Record, 0, len(blocks))
+
+	for _, block := range blocks {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+
+		lines := strings.Split(block, "\n")
+		doi := ""
+		for _, line := range lines {
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, "DO") {
+				if idx := strings.Index(trimmed, "-"); idx != -1 {
+					doi = validDOI(trimmed[idx+1:])
+				}
+				break
+			}
+		}
+
+		records = append(records, Record{Lines: lines, DOI: doi})
+	}
+
+	return records
+}
+
+// ---- BibTeX ----
+
+type bibtexParser struct{}
+
+func (p *bibtexParser) Name() string         { return "bibtex" }
+func (p *bibtexParser) Extensions() []string { return []string{".bib"} }
+func (p *bibtexParser) Sniff(content string) bool {
+	return strings.Contains(content, "@")
+}
+
+var bibtexDOIPattern = regexp.MustCompile(`(?i)doi\s*=\s*[{"]([^}"]+)[}"]`)
+
+func (p *bibtexParser) ParseRecords(content string, opts ParseOptions) []Record {
+	entries := splitBibtexEntries(content)
+	records := make([]Record, 0, len(entries))
+
+	for _, entry := range entries {
+		doi := ""
+		if match := bibtexDOIPattern.FindStringSubmatch(entry); len(match) > 1 {
+			doi = validDOI(match[1])
+		}
+		records = append(records, Record{Lines: strings.Split(entry, "\n"), DOI: doi})
+	}
+
+	return records
+}
+
+// splitBibtexEntries 粗略地按顶层 "@" 条目切分 BibTeX 内容
+func splitBibtexEntries(content string) []string {
+	entries := make([]string, 0)
+	var current strings.Builder
+	depth := 0
+	started := false
+
+	for _, r := range content {
+		if r == '@' && depth == 0 {
+			if started {
+				entries = append(entries, current.String())
+				current.Reset()
+			}
+			started = true
+		}
+		if started {
+			current.WriteRune(r)
+		}
+		if r == '{' {
+			depth++
+		} else if r == '}' {
+			depth--
+		}
+	}
+	if started && current.Len() > 0 {
+		entries = append(entries, current.String())
+	}
+
+	return entries
+}
+
+// ---- EndNote XML ----
+
+type endnoteXMLParser struct{}
+
+func (p *endnoteXMLParser) Name() string         { return "endnote-xml" }
+func (p *endnoteXMLParser) Extensions() []string { return []string{".xml"} }
+func (p *endnoteXMLParser) Sniff(content string) bool {
+	return strings.Contains(content, "<xml>") && strings.Contains(content, "<record>")
+}
+
+var endnoteDOIPattern = regexp.MustCompile(`(?is)<electronic-resource-num>(.*?)</electronic-resource-num>`)
+
+func (p *endnoteXMLParser) ParseRecords(content string, opts ParseOptions) []Record {
+	blocks := strings.Split(content, "<record>")
+	records := make([]Record, 0, len(blocks))
+
+	for i, block := range blocks {
+		if i == 0 {
+			continue // 第一段是 <record> 之前的头部信息
+		}
+
+		doi := ""
+		if match := endnoteDOIPattern.FindStringSubmatch(block); len(match) > 1 {
+			doi = validDOI(stripXMLTags(match[1]))
+		}
+
+		records = append(records, Record{Lines: []string{block}, DOI: doi})
+	}
+
+	return records
+}
+
+func stripXMLTags(s string) string {
+	var b strings.Builder
+	inTag := false
+	for _, r := range s {
+		switch r {
+		case '<':
+			inTag = true
+		case '>':
+			inTag = false
+		default:
+			if !inTag {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+// ---- CSL-JSON / CrossRef JSON 数组 ----
+
+type cslJSONParser struct{}
+
+func (p *cslJSONParser) Name() string         { return "csl-json" }
+func (p *cslJSONParser) Extensions() []string { return []string{".json"} }
+func (p *cslJSONParser) Sniff(content string) bool {
+	trimmed := strings.TrimSpace(content)
+	return strings.HasPrefix(trimmed, "[")
+}
+
+func (p *cslJSONParser) ParseRecords(content string, opts ParseOptions) []Record {
+	var items []map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &items); err != nil {
+		return nil
+	}
+
+	records := make([]Record, 0, len(items))
+	for _, item := range items {
+		doi := ""
+		if v, ok := item["DOI"]; ok {
+			doi = validDOI(toStringValue(v))
+		} else if v, ok := item["doi"]; ok {
+			doi = validDOI(toStringValue(v))
+		}
+
+		data, _ := json.Marshal(item)
+		records = append(records, Record{Lines: []string{string(data)}, DOI: doi})
+	}
+
+	return records
+}
+
+func toStringValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return ""
+}
+
+// ---- 通用 CSV（--doi-column） ----
+
+type csvParser struct{}
+
+func (p *csvParser) Name() string         { return "csv" }
+func (p *csvParser) Extensions() []string { return []string{".csv"} }
+func (p *csvParser) Sniff(content string) bool {
+	return strings.Contains(content, ",")
+}
+
+func (p *csvParser) ParseRecords(content string, opts ParseOptions) []Record {
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.FieldsPerRecord = -1
+
+	rows, err := reader.ReadAll()
+	if err != nil || len(rows) == 0 {
+		return nil
+	}
+
+	columnName := strings.ToLower(strings.TrimSpace(opts.DOIColumn))
+	if columnName == "" {
+		columnName = "doi"
+	}
+
+	header := rows[0]
+	doiCol := -1
+	for i, h := range header {
+		if strings.ToLower(strings.TrimSpace(h)) == columnName {
+			doiCol = i
+			break
+		}
+	}
+	if doiCol == -1 {
+		return nil
+	}
+
+	records := make([]Record, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		doi := ""
+		if doiCol < len(row) {
+			doi = validDOI(row[doiCol])
+		}
+		records = append(records, Record{Lines: []string{strings.Join(row, ",")}, DOI: doi})
+	}
+
+	return records
+}
+
+// ---- Zotero SQLite 导出库 ----
+
+// zoteroSQLiteParser 通过 itemData/itemDataValues/fields 表的联合查询提取 DOI。
+// 驱动由 cmd/main.go 以 blank import 的方式注册（modernc.org/sqlite，纯 Go 实现，
+// 不需要 cgo），本包只管按名字找已注册的驱动，不关心具体是哪一个。
+type zoteroSQLiteParser struct{}
+
+func (p *zoteroSQLiteParser) Name() string         { return "zotero-sqlite" }
+func (p *zoteroSQLiteParser) Extensions() []string { return []string{".sqlite"} }
+func (p *zoteroSQLiteParser) Sniff(content string) bool {
+	return strings.HasPrefix(content, "SQLite format 3")
+}
+
+func (p *zoteroSQLiteParser) ParseRecords(content string, opts ParseOptions) []Record {
+	// Zotero 库是二进制 SQLite 文件，不能像其他格式一样传入已读取的文本内容解析。
+	// 真正的实现在 extractZoteroRecords 中，通过文件路径直接打开数据库查询。
+	return nil
+}
+
+// zoteroSQLiteDriverNames 按优先级列出本包能够使用的 database/sql 驱动名。
+// modernc.org/sqlite（本仓库在 cmd/main.go 里 blank-import 的那个）注册为 "sqlite"；
+// 也接受 "sqlite3"，以兼容有人在外部改接了 mattn/go-sqlite3（cgo）的场景。
+var zoteroSQLiteDriverNames = []string{"sqlite", "sqlite3"}
+
+// zoteroDriverRegistered 检查 database/sql 当前是否已经注册了可用的 sqlite 驱动，
+// 注册了的话返回其驱动名供 sql.Open 使用。
+func zoteroDriverRegistered() (string, bool) {
+	registered := make(map[string]bool)
+	for _, name := range sql.Drivers() {
+		registered[name] = true
+	}
+	for _, name := range zoteroSQLiteDriverNames {
+		if registered[name] {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// extractZoteroRecords 直接打开 Zotero 的 .sqlite 文件，
+// 联表 itemData/itemDataValues/fields 读出所有 fieldName = 'DOI' 的值。
+// 驱动由 cmd/main.go blank-import modernc.org/sqlite 注册；没有注册驱动时
+// 直接返回一条明确的错误，而不是让 sql.Open 产生的 "unknown driver" 被上层悄悄吞掉。
+func extractZoteroRecords(dbPath string) ([]Record, error) {
+	driverName, ok := zoteroDriverRegistered()
+	if !ok {
+		return nil, fmt.Errorf("Zotero 支持未编译：未注册 sqlite 驱动（预期由 cmd/main.go blank-import modernc.org/sqlite 注册），无法解析 %s", dbPath)
+	}
+
+	db, err := sql.Open(driverName, dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开 Zotero 数据库: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`
+		SELECT itemDataValues.value
+		FROM itemData
+		JOIN itemDataValues ON itemData.valueID = itemDataValues.valueID
+		JOIN fields ON itemData.fieldID = fields.fieldID
+		WHERE fields.fieldName = 'DOI'
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("查询 Zotero DOI 字段失败: %v", err)
+	}
+	defer rows.Close()
+
+	records := make([]Record, 0)
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			continue
+		}
+		records = append(records, Record{Lines: []string{value}, DOI: validDOI(value)})
+	}
+
+	return records, rows.Err()
+}
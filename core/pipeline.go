@@ -0,0 +1,224 @@
+// core/pipeline.go
+
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// PipelineOptions 配置 RunPipeline 的一次运行
+type PipelineOptions struct {
+	PDFDir        string
+	MaxWorkers    int
+	Pool          *MirrorPool
+	Manifest      *Manifest
+	History       *History   // 可为 nil，表示不做跨运行的成功/失败退避记录
+	Proxies       *ProxyPool // 可为 nil，表示不经代理、全部直连
+	Resolvers     []Resolver
+	Bus           *EventBus            // 可为 nil，表示不发布事件
+	RotLogger     *RotatingLogger      // 可为 nil；非空时每个终态结果都会被立即追加写入 JSONL 分片
+	CaptchaSolver CaptchaSolver        // 可为 nil，表示遇到验证码直接放弃（等价于 NoopCaptchaSolver）
+	QueueSize     int                  // 进入调度器之前的背压信号量大小，<=0 时默认为 MaxWorkers*4
+	Progress      chan<- ProgressEvent // 可为 nil；非空时接收字节级的实时下载进度，发送非阻塞
+	Logger        StructuredLogger     // 可为 nil，等价于 NoopStructuredLogger
+}
+
+// DOIJob 是提交给 RunPipeline 的一个待处理 DOI 及其优先级
+type DOIJob struct {
+	DOI      string
+	Priority int // 数值越大越先被处理，默认 0
+}
+
+// RunPipeline 以流水线方式处理 DOI：一个 DOISource 协程从 doiSource channel 读取 DOIJob，
+// 依次检查 Manifest（文件仍在且完整）与 History（历史上已成功，或失败后仍在退避期内），
+// 存活下来的任务按优先级送入 PriorityScheduler（有界信号量提供背压），固定数量的 worker
+// 从调度器中弹出最高优先级的任务并下载，最终由本函数汇总结果并通过 opts.Bus 发布结构化事件。
+// doiSource 由调用方负责关闭；当它关闭且所有 worker 完成后，RunPipeline 返回。
+func RunPipeline(doiSource <-chan DOIJob, opts PipelineOptions) (*DownloadStats, error) {
+	if err := os.MkdirAll(opts.PDFDir, 0755); err != nil {
+		return nil, fmt.Errorf("无法创建 PDF 目录: %v", err)
+	}
+
+	maxWorkers := opts.MaxWorkers
+	if maxWorkers <= 0 {
+		maxWorkers = 1
+	}
+
+	queueSize := opts.QueueSize
+	if queueSize <= 0 {
+		queueSize = maxWorkers * 4
+	}
+
+	stats := &DownloadStats{
+		Errors:      make([]DownloadError, 0),
+		AllTimes:    make([]time.Duration, 0),
+		SuccessTime: make([]time.Duration, 0),
+	}
+
+	transport := &http.Transport{
+		MaxIdleConns:        maxWorkers * 2,
+		MaxIdleConnsPerHost: maxWorkers,
+		MaxConnsPerHost:     maxWorkers * 2,
+		IdleConnTimeout:     90 * time.Second,
+		DisableKeepAlives:   false,
+		ForceAttemptHTTP2:   true,
+	}
+
+	// sharedClient 需要带 cookie jar：验证码表单提交依赖与上一次页面请求相同的会话状态
+	jar, _ := cookiejar.New(nil)
+	sharedClient := &http.Client{Transport: transport, Timeout: 10 * time.Second, Jar: jar}
+	pdfClient := &http.Client{Transport: transport, Timeout: 30 * time.Second}
+
+	scheduler := NewPriorityScheduler()
+	admission := make(chan struct{}, queueSize) // 背压信号量：替代原先有界 jobs channel 的容量限制
+	results := make(chan DownloadResult, queueSize)
+
+	var produced, preSkipped int
+	var countMu sync.Mutex
+
+	// DOISource：从输入 channel 读取 DOIJob，按清单/历史记录去重后，
+	// 获取一个背压信号量配额，再按优先级送入调度器
+	var sourceWg sync.WaitGroup
+	sourceWg.Add(1)
+	go func() {
+		defer sourceWg.Done()
+		for job := range doiSource {
+			doi := job.DOI
+			if opts.Manifest != nil && opts.Manifest.ShouldSkip(doi, opts.PDFDir) {
+				countMu.Lock()
+				preSkipped++
+				countMu.Unlock()
+				continue
+			}
+			if opts.History != nil {
+				if opts.History.ShouldSkip(doi) {
+					countMu.Lock()
+					preSkipped++
+					countMu.Unlock()
+					continue
+				}
+				if !opts.History.ShouldRetryNow(doi) {
+					countMu.Lock()
+					preSkipped++
+					countMu.Unlock()
+					continue
+				}
+			}
+			countMu.Lock()
+			produced++
+			countMu.Unlock()
+
+			opts.Bus.publish(JobQueued{DOI: doi})
+			publishProgress(opts.Progress, ProgressEvent{DOI: doi, Stage: StageQueued})
+			admission <- struct{}{}
+			scheduler.Push(PriorityJob{DOI: doi, Priority: job.Priority})
+		}
+		scheduler.Close()
+	}()
+
+	var workerWg sync.WaitGroup
+	for i := 0; i < maxWorkers; i++ {
+		workerWg.Add(1)
+		go func() {
+			defer workerWg.Done()
+			for {
+				pjob, ok := scheduler.Pop()
+				if !ok {
+					return
+				}
+				<-admission
+				startTime := time.Now()
+				doi := pjob.DOI
+
+				opts.Bus.publish(JobStarted{DOI: doi})
+
+				result, sourceLabel := resolveAndDownload(doi, opts.Resolvers, opts.Pool, opts.PDFDir, sharedClient, pdfClient, opts.Bus, opts.CaptchaSolver, opts.Proxies, opts.Progress, opts.Logger)
+
+				if opts.Manifest != nil {
+					entry := ManifestEntry{
+						DOI: result.DOI, Status: result.Status, Filename: result.Filename,
+						Size: result.Size, SourceMirror: sourceLabel,
+					}
+					if result.Status == "success" {
+						if sum, err := sha256File(filepath.Join(opts.PDFDir, result.Filename)); err == nil {
+							entry.SHA256 = sum
+						}
+						entry.HTTPStatus = http.StatusOK
+					}
+					if err := opts.Manifest.Record(entry); err != nil {
+						fmt.Fprintf(os.Stderr, "⚠️  写入清单失败 (DOI: %s): %v\n", result.DOI, err)
+					}
+				}
+
+				if opts.History != nil && result.Status != "skip" {
+					if err := opts.History.Record(doi, result.Status == "success", result.Error); err != nil {
+						fmt.Fprintf(os.Stderr, "⚠️  写入历史记录失败 (DOI: %s): %v\n", result.DOI, err)
+					}
+				}
+
+				result.Duration = time.Since(startTime)
+
+				if opts.RotLogger != nil {
+					if err := opts.RotLogger.LogResult(result, sourceLabel); err != nil {
+						fmt.Fprintf(os.Stderr, "⚠️  写入滚动日志失败 (DOI: %s): %v\n", result.DOI, err)
+					}
+				}
+
+				if result.Status == "success" {
+					opts.Bus.publish(JobSucceeded{DOI: result.DOI, Bytes: result.Size, Elapsed: result.Duration})
+				} else if result.Status == "failed" {
+					opts.Bus.publish(JobFailed{DOI: result.DOI, Err: result.Error, Mirror: sourceLabel})
+				}
+
+				results <- result
+			}
+		}()
+	}
+
+	startTime := time.Now()
+
+	go func() {
+		sourceWg.Wait()
+		workerWg.Wait()
+		close(results)
+	}()
+
+	for result := range results {
+		stats.AllTimes = append(stats.AllTimes, result.Duration)
+		switch result.Status {
+		case "success":
+			stats.Success++
+			stats.TotalSize += result.Size
+			stats.SuccessTime = append(stats.SuccessTime, result.Duration)
+			if stats.ResolverCounts == nil {
+				stats.ResolverCounts = make(map[string]int)
+			}
+			stats.ResolverCounts[result.ResolverName]++
+		case "skip":
+			stats.Skip++
+		case "failed":
+			stats.Failed++
+			stats.Errors = append(stats.Errors, DownloadError{
+				URL: result.URL, DOI: result.DOI, Error: result.Error, Time: time.Now(),
+			})
+		}
+	}
+
+	countMu.Lock()
+	stats.Total = produced + preSkipped
+	stats.Skip += preSkipped
+	countMu.Unlock()
+
+	stats.TotalTime = time.Since(startTime)
+	if opts.Pool != nil {
+		stats.Mirrors = opts.Pool.Snapshot()
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,60 @@
+// core/progress.go
+
+package core
+
+import "io"
+
+// ProgressStage 描述单个 DOI 在下载流程中所处的阶段
+type ProgressStage string
+
+const (
+	StageQueued       ProgressStage = "queued"
+	StageFetchingPage ProgressStage = "fetching-page"
+	StageExtracting   ProgressStage = "extracting"
+	StageDownloading  ProgressStage = "downloading"
+	StageVerifying    ProgressStage = "verifying"
+	StageDone         ProgressStage = "done"
+	StageFailed       ProgressStage = "failed"
+)
+
+// ProgressEvent 是下载过程中一次细粒度的进度汇报。它比 EventBus 里 JobStarted/JobSucceeded
+// 这类任务级事件更高频——比如一个大文件的分块下载会在每个分块完成时各汇报一次 BytesDone，
+// 供需要实时进度条/百分比的 UI 消费；BytesTotal<=0 表示总大小未知（如尚未拿到 Content-Length）。
+type ProgressEvent struct {
+	DOI        string
+	Stage      ProgressStage
+	BytesDone  int64
+	BytesTotal int64
+	Attempt    int
+}
+
+// publishProgress 向 ch 发送一次进度事件，ch 为 nil 时什么也不做；发送是非阻塞的，
+// 消费者跟不上时直接丢弃本次进度汇报，不能让下载 worker 被一个满的 channel 拖慢。
+func publishProgress(ch chan<- ProgressEvent, evt ProgressEvent) {
+	if ch == nil {
+		return
+	}
+	select {
+	case ch <- evt:
+	default:
+	}
+}
+
+// countingReader 包装一个 io.Reader，每次 Read 都把累计读取的字节数报告给 onRead，
+// 用于在 io.Copy/io.ReadAll 期间产生字节级的下载进度。
+type countingReader struct {
+	r      io.Reader
+	total  int64
+	onRead func(done int64)
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		c.total += int64(n)
+		if c.onRead != nil {
+			c.onRead(c.total)
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,228 @@
+// core/rangedownload.go
+
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// 超过该大小且服务器支持 Range 请求时，改用分块并发下载
+const rangeDownloadThreshold = 2 * 1024 * 1024 // 2 MB
+const defaultRangeChunks = 4
+
+// errRangeNotSupported 是一个哨兵错误：表示分块请求实际拿到的不是 206，说明这条链路
+// （mirror、CDN 或中间代理）并不真的支持 Range 请求，而不是一次普通的网络/服务器错误。
+// 调用方（downloadSinglePDF 的分块分支）应该据此回退到单流下载，
+// 而不是像其他分块错误那样直接把 DOI 标记为失败。
+var errRangeNotSupported = errors.New("服务器未对 Range 请求返回 206 Partial Content")
+
+// chunkState 记录单个分块的下载进度，用于落盘恢复
+type chunkState struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"` // 含 To 本身（HTTP Range 语义）
+	Done bool  `json:"done"`
+}
+
+// chunkSidecar 是分块下载的落盘状态文件，与目标文件一一对应
+type chunkSidecar struct {
+	Size   int64        `json:"size"`
+	Chunks []chunkState `json:"chunks"`
+}
+
+func chunkSidecarPath(destPath string) string {
+	return destPath + ".chunks.json"
+}
+
+func chunkPartPath(destPath string) string {
+	return destPath + ".part"
+}
+
+// loadOrCreateSidecar 加载已有的分块状态（用于断点续传），若不存在或文件大小对不上则重新切分
+func loadOrCreateSidecar(sidecarPath string, size int64, numChunks int) (*chunkSidecar, error) {
+	if data, err := os.ReadFile(sidecarPath); err == nil {
+		var sidecar chunkSidecar
+		if json.Unmarshal(data, &sidecar) == nil && sidecar.Size == size && len(sidecar.Chunks) > 0 {
+			return &sidecar, nil
+		}
+	}
+
+	if numChunks < 1 {
+		numChunks = 1
+	}
+	chunkSize := size / int64(numChunks)
+	if chunkSize < 1 {
+		chunkSize = size
+		numChunks = 1
+	}
+
+	chunks := make([]chunkState, 0, numChunks)
+	var from int64
+	for i := 0; i < numChunks; i++ {
+		to := from + chunkSize - 1
+		if i == numChunks-1 || to >= size-1 {
+			to = size - 1
+		}
+		chunks = append(chunks, chunkState{From: from, To: to})
+		from = to + 1
+		if from >= size {
+			break
+		}
+	}
+
+	return &chunkSidecar{Size: size, Chunks: chunks}, nil
+}
+
+// save 原子地把分块状态写回磁盘（先写临时文件再 rename，避免写一半被读到）
+func (s *chunkSidecar) save(sidecarPath string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := sidecarPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, sidecarPath)
+}
+
+// downloadRangedPDF 把 pdfURL 指向的大文件切分成若干 Range 请求并发下载到 destPath。
+// 每个分块的 from/to/done 状态记录在 destPath 同目录的 sidecar JSON 中：若进程中途被杀死，
+// 下次调用会跳过已完成的分块，只重新请求缺失的部分。所有分块都写入同一个预分配的 .part 文件，
+// 全部完成后把 .part 重命名为 destPath、删除 sidecar，返回文件总字节数；PDF 文件头不在这里校验。
+// progress 可为 nil；非空时每个分块每读到一部分数据都会汇报一次累计字节数——多个分块
+// 并发写入同一个计数器，因此用 progressMu 保护，不能像单连接下载那样直接用 countingReader。
+func downloadRangedPDF(doi, pdfURL, referer, destPath string, size int64, client *http.Client, progress chan<- ProgressEvent) (int64, error) {
+	partPath := chunkPartPath(destPath)
+	sidecarPath := chunkSidecarPath(destPath)
+
+	sidecar, err := loadOrCreateSidecar(sidecarPath, size, defaultRangeChunks)
+	if err != nil {
+		return 0, fmt.Errorf("无法初始化分块状态: %v", err)
+	}
+
+	file, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("无法创建分块下载文件: %v", err)
+	}
+	if err := file.Truncate(size); err != nil {
+		file.Close()
+		return 0, fmt.Errorf("无法预分配分块下载文件: %v", err)
+	}
+
+	var (
+		wg           sync.WaitGroup
+		mu           sync.Mutex
+		firstErr     error
+		progressMu   sync.Mutex
+		bytesDoneSum int64
+	)
+	reportProgress := func(delta int64) {
+		progressMu.Lock()
+		bytesDoneSum += delta
+		done := bytesDoneSum
+		progressMu.Unlock()
+		publishProgress(progress, ProgressEvent{DOI: doi, Stage: StageDownloading, BytesDone: done, BytesTotal: size})
+	}
+
+	for i := range sidecar.Chunks {
+		chunk := sidecar.Chunks[i]
+		if chunk.Done {
+			continue
+		}
+
+		wg.Add(1)
+		go func(idx int, chunk chunkState) {
+			defer wg.Done()
+
+			if err := fetchChunk(pdfURL, referer, client, chunk, file, reportProgress); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				return
+			}
+
+			mu.Lock()
+			sidecar.Chunks[idx].Done = true
+			_ = sidecar.save(sidecarPath)
+			mu.Unlock()
+		}(i, chunk)
+	}
+
+	wg.Wait()
+	file.Close()
+
+	if firstErr != nil {
+		// 保留 .part 和 sidecar，下次调用时可以从已完成的分块继续
+		return 0, firstErr
+	}
+
+	if err := os.Rename(partPath, destPath); err != nil {
+		return 0, fmt.Errorf("合并分块文件失败: %v", err)
+	}
+	os.Remove(sidecarPath)
+
+	return size, nil
+}
+
+// fetchChunk 下载单个分块并写入 file 对应的偏移位置；onRead（可为 nil）在每次读到数据时
+// 被调用一次，传入本次 Read 新增的字节数，用于汇总多个分块的合并下载进度。
+func fetchChunk(pdfURL, referer string, client *http.Client, chunk chunkState, file *os.File, onRead func(delta int64)) error {
+	req, err := http.NewRequest("GET", pdfURL, nil)
+	if err != nil {
+		return fmt.Errorf("创建分块请求失败: %v", err)
+	}
+	setBrowserHeaders(req)
+	req.Header.Set("Referer", referer)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", chunk.From, chunk.To))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("分块请求失败 (%d-%d): %v", chunk.From, chunk.To, err)
+	}
+	defer resp.Body.Close()
+
+	// 只接受 206：某些服务器/中间代理会忽略 Range 头整体返回 200 和完整内容，
+	// 此时每个分块协程都会把整份文件各自写到自己的 From 偏移上，产生互相覆盖的
+	// 越界写入——文件头恰好落在偏移 0 的分块上，会被 validatePDFHeader 误判为成功，
+	// 实际产出的是一个损坏的文件。因此 200 在这里必须视为失败，而不是当成成功分块接受；
+	// 包装成 errRangeNotSupported，让 downloadRangedPDF 的调用方改走单流下载，而不是
+	// 把整个 DOI 直接判定为下载失败。
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("%w（实际 HTTP %d，服务器可能忽略了 Range 头），放弃分块下载 (%d-%d)", errRangeNotSupported, resp.StatusCode, chunk.From, chunk.To)
+	}
+
+	var reader io.Reader = resp.Body
+	if onRead != nil {
+		var prev int64
+		reader = &countingReader{r: resp.Body, onRead: func(total int64) {
+			onRead(total - prev)
+			prev = total
+		}}
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("读取分块内容失败 (%d-%d): %v", chunk.From, chunk.To, err)
+	}
+
+	if _, err := file.WriteAt(data, chunk.From); err != nil {
+		return fmt.Errorf("写入分块失败 (%d-%d): %v", chunk.From, chunk.To, err)
+	}
+
+	return nil
+}
+
+// supportsRangeDownload 判断一次 PDF 响应的头部是否满足改用分块并发下载的条件
+func supportsRangeDownload(acceptRanges string, contentLength int64) bool {
+	return contentLength > rangeDownloadThreshold && strings.Contains(strings.ToLower(acceptRanges), "bytes")
+}
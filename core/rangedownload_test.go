@@ -0,0 +1,104 @@
+// core/rangedownload_test.go
+
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadOrCreateSidecarSplitsIntoChunks(t *testing.T) {
+	sidecarPath := filepath.Join(t.TempDir(), "paper.pdf.chunks.json")
+
+	sidecar, err := loadOrCreateSidecar(sidecarPath, 1000, 4)
+	if err != nil {
+		t.Fatalf("loadOrCreateSidecar() error = %v", err)
+	}
+
+	if len(sidecar.Chunks) != 4 {
+		t.Fatalf("len(Chunks) = %d, want 4", len(sidecar.Chunks))
+	}
+	if sidecar.Chunks[0].From != 0 {
+		t.Fatalf("Chunks[0].From = %d, want 0", sidecar.Chunks[0].From)
+	}
+	if last := sidecar.Chunks[len(sidecar.Chunks)-1].To; last != 999 {
+		t.Fatalf("last chunk To = %d, want 999 (size-1)", last)
+	}
+	// 分块之间不能留缺口也不能重叠：下一块的 From 必须紧接上一块的 To
+	for i := 1; i < len(sidecar.Chunks); i++ {
+		if sidecar.Chunks[i].From != sidecar.Chunks[i-1].To+1 {
+			t.Fatalf("chunk %d.From = %d, want %d (contiguous with previous chunk)", i, sidecar.Chunks[i].From, sidecar.Chunks[i-1].To+1)
+		}
+	}
+}
+
+func TestLoadOrCreateSidecarResumesFromDiskWhenSizeMatches(t *testing.T) {
+	sidecarPath := filepath.Join(t.TempDir(), "paper.pdf.chunks.json")
+
+	// 模拟上一次运行中途被杀死：已经落盘了一份 sidecar，其中一个分块标记为完成
+	onDisk := &chunkSidecar{
+		Size: 1000,
+		Chunks: []chunkState{
+			{From: 0, To: 249, Done: true},
+			{From: 250, To: 499, Done: false},
+			{From: 500, To: 749, Done: false},
+			{From: 750, To: 999, Done: false},
+		},
+	}
+	data, err := json.Marshal(onDisk)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if err := os.WriteFile(sidecarPath, data, 0644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	resumed, err := loadOrCreateSidecar(sidecarPath, 1000, 4)
+	if err != nil {
+		t.Fatalf("loadOrCreateSidecar() error = %v", err)
+	}
+
+	if len(resumed.Chunks) != 4 {
+		t.Fatalf("len(Chunks) = %d, want the on-disk 4 chunks to be reused", len(resumed.Chunks))
+	}
+	if !resumed.Chunks[0].Done {
+		t.Fatalf("Chunks[0].Done = false, want the already-completed chunk to survive the resume")
+	}
+	if resumed.Chunks[1].Done || resumed.Chunks[2].Done || resumed.Chunks[3].Done {
+		t.Fatalf("an unfinished chunk was incorrectly marked Done after resume: %+v", resumed.Chunks)
+	}
+}
+
+func TestLoadOrCreateSidecarDiscardsStaleStateWhenSizeMismatches(t *testing.T) {
+	sidecarPath := filepath.Join(t.TempDir(), "paper.pdf.chunks.json")
+
+	// 文件大小和上次不一致（比如服务器返回了不同内容），旧的 sidecar 必须被丢弃重新切分，
+	// 否则 Done 标记会错误地对应到新内容里完全不同的字节区间
+	stale := &chunkSidecar{
+		Size:   1000,
+		Chunks: []chunkState{{From: 0, To: 999, Done: true}},
+	}
+	data, err := json.Marshal(stale)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if err := os.WriteFile(sidecarPath, data, 0644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	fresh, err := loadOrCreateSidecar(sidecarPath, 2000, 4)
+	if err != nil {
+		t.Fatalf("loadOrCreateSidecar() error = %v", err)
+	}
+
+	if fresh.Size != 2000 {
+		t.Fatalf("Size = %d, want 2000 (freshly recomputed)", fresh.Size)
+	}
+	for _, c := range fresh.Chunks {
+		if c.Done {
+			t.Fatalf("chunk %+v is marked Done in a freshly re-split sidecar, want all chunks pending", c)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// core/resolver.go
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ResolverCandidate 是某个 Resolver 为一个 DOI 给出的候选下载目标。
+// Kind 为 "page" 时 URL 指向一个需要像 Sci-Hub 那样抓取 HTML 再提取 PDF 链接的页面；
+// Kind 为 "pdf" 时 URL 已经是可以直接下载的 PDF 地址。
+type ResolverCandidate struct {
+	Kind   string // "page" or "pdf"
+	URL    string
+	Mirror *Mirror // 仅 SciHubResolver 填充，供调用方回写镜像健康统计
+}
+
+// Resolver 将一个 DOI 解析为候选的 PDF（或待抓取页面）地址
+type Resolver interface {
+	Name() string
+	Resolve(doi string, client *http.Client) (ResolverCandidate, error)
+}
+
+// SciHubResolver 通过镜像池把 DOI 拼成 Sci-Hub 页面 URL，复用现有的 HTML 抓取逻辑
+type SciHubResolver struct {
+	Pool *MirrorPool
+}
+
+// NewSciHubResolver 创建一个基于镜像池的 SciHubResolver
+func NewSciHubResolver(pool *MirrorPool) *SciHubResolver {
+	return &SciHubResolver{Pool: pool}
+}
+
+func (r *SciHubResolver) Name() string { return "sci-hub" }
+
+func (r *SciHubResolver) Resolve(doi string, client *http.Client) (ResolverCandidate, error) {
+	mirror := r.Pool.Best(doi)
+	if mirror == nil {
+		return ResolverCandidate{}, fmt.Errorf("所有镜像均处于隔离状态，等待超时")
+	}
+	return ResolverCandidate{
+		Kind:   "page",
+		URL:    mirrorPageURL(mirror, doi),
+		Mirror: mirror,
+	}, nil
+}
+
+// mirrorPageURL 拼出某个 Sci-Hub 镜像上一个 DOI 对应的页面地址
+func mirrorPageURL(mirror *Mirror, doi string) string {
+	return fmt.Sprintf("%s/%s", mirror.BaseURL, doi)
+}
+
+// UnpaywallResolver 查询 Unpaywall API，返回其 best_oa_location 给出的合法 PDF 地址
+type UnpaywallResolver struct {
+	Email string
+}
+
+// NewUnpaywallResolver 创建一个 Unpaywall 解析器，email 为 Unpaywall API 要求的联系邮箱
+func NewUnpaywallResolver(email string) *UnpaywallResolver {
+	return &UnpaywallResolver{Email: email}
+}
+
+func (r *UnpaywallResolver) Name() string { return "unpaywall" }
+
+func (r *UnpaywallResolver) Resolve(doi string, client *http.Client) (ResolverCandidate, error) {
+	if r.Email == "" {
+		return ResolverCandidate{}, fmt.Errorf("unpaywall 解析器需要配置 email")
+	}
+
+	apiURL := fmt.Sprintf("https://api.unpaywall.org/v2/%s?email=%s", url.PathEscape(doi), url.QueryEscape(r.Email))
+	resp, err := client.Get(apiURL)
+	if err != nil {
+		return ResolverCandidate{}, fmt.Errorf("unpaywall 请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ResolverCandidate{}, fmt.Errorf("unpaywall 返回 HTTP %d", resp.StatusCode)
+	}
+
+	var body struct {
+		BestOaLocation struct {
+			URLForPDF string `json:"url_for_pdf"`
+		} `json:"best_oa_location"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return ResolverCandidate{}, fmt.Errorf("unpaywall 响应解析失败: %v", err)
+	}
+
+	if body.BestOaLocation.URLForPDF == "" {
+		return ResolverCandidate{}, fmt.Errorf("unpaywall 未返回开放获取 PDF")
+	}
+
+	return ResolverCandidate{Kind: "pdf", URL: body.BestOaLocation.URLForPDF}, nil
+}
+
+// CrossRefResolver 查询 CrossRef works API，在 link 数组里寻找 content-type 为 application/pdf 的条目
+type CrossRefResolver struct{}
+
+// NewCrossRefResolver 创建一个 CrossRef 解析器
+func NewCrossRefResolver() *CrossRefResolver {
+	return &CrossRefResolver{}
+}
+
+func (r *CrossRefResolver) Name() string { return "crossref" }
+
+func (r *CrossRefResolver) Resolve(doi string, client *http.Client) (ResolverCandidate, error) {
+	apiURL := fmt.Sprintf("https://api.crossref.org/works/%s", url.PathEscape(doi))
+	resp, err := client.Get(apiURL)
+	if err != nil {
+		return ResolverCandidate{}, fmt.Errorf("crossref 请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ResolverCandidate{}, fmt.Errorf("crossref 返回 HTTP %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Message struct {
+			Link []struct {
+				URL         string `json:"URL"`
+				ContentType string `json:"content-type"`
+			} `json:"link"`
+		} `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return ResolverCandidate{}, fmt.Errorf("crossref 响应解析失败: %v", err)
+	}
+
+	for _, link := range body.Message.Link {
+		if strings.EqualFold(link.ContentType, "application/pdf") && link.URL != "" {
+			return ResolverCandidate{Kind: "pdf", URL: link.URL}, nil
+		}
+	}
+
+	return ResolverCandidate{}, fmt.Errorf("crossref 未提供 PDF 链接")
+}
+
+// OpenAccessButtonResolver 查询 Open Access Button 的 find API
+type OpenAccessButtonResolver struct{}
+
+// NewOpenAccessButtonResolver 创建一个 Open Access Button 解析器
+func NewOpenAccessButtonResolver() *OpenAccessButtonResolver {
+	return &OpenAccessButtonResolver{}
+}
+
+func (r *OpenAccessButtonResolver) Name() string { return "openaccessbutton" }
+
+func (r *OpenAccessButtonResolver) Resolve(doi string, client *http.Client) (ResolverCandidate, error) {
+	apiURL := fmt.Sprintf("https://api.openaccessbutton.org/find?id=%s", url.QueryEscape(doi))
+	resp, err := client.Get(apiURL)
+	if err != nil {
+		return ResolverCandidate{}, fmt.Errorf("openaccessbutton 请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ResolverCandidate{}, fmt.Errorf("openaccessbutton 返回 HTTP %d", resp.StatusCode)
+	}
+
+	var body struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return ResolverCandidate{}, fmt.Errorf("openaccessbutton 响应解析失败: %v", err)
+	}
+
+	if body.URL == "" {
+		return ResolverCandidate{}, fmt.Errorf("openaccessbutton 未找到开放获取版本")
+	}
+
+	return ResolverCandidate{Kind: "pdf", URL: body.URL}, nil
+}
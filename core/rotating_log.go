@@ -0,0 +1,241 @@
+// core/rotating_log.go
+
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认的日志滚动阈值
+const (
+	defaultLogRotateSize = 16 * 1024 * 1024 // 16 MiB
+	defaultLogRotateAge  = 1 * time.Hour
+)
+
+// rotatingLogEntry 是写入 JSONL 分片的单条记录
+type rotatingLogEntry struct {
+	DOI       string `json:"doi"`
+	Status    string `json:"status"`
+	Mirror    string `json:"mirror,omitempty"`
+	ElapsedMs int64  `json:"elapsed_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// RotatingLogger 在长批次下载过程中逐条追加写入 JSONL 分片，
+// 超过大小或时间阈值时滚动到新文件，避免单个日志文件无限增长，
+// 并保证进程被杀死时已写入的结果不会丢失。
+type RotatingLogger struct {
+	LogDir    string
+	Timestamp string
+
+	RotateSize int64
+	RotateAge  time.Duration
+
+	mu        sync.Mutex
+	file      *os.File
+	writer    *bufio.Writer
+	seq       int
+	size      int64
+	openedAt  time.Time
+}
+
+// NewRotatingLogger 创建一个新的滚动日志记录器，使用默认的 16 MiB / 1 小时滚动阈值
+func NewRotatingLogger(baseDir string) (*RotatingLogger, error) {
+	logDir := filepath.Join(baseDir, "logs")
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil, fmt.Errorf("无法创建日志目录: %v", err)
+	}
+
+	l := &RotatingLogger{
+		LogDir:     logDir,
+		Timestamp:  time.Now().Format("2006-01-02_15-04-05"),
+		RotateSize: defaultLogRotateSize,
+		RotateAge:  defaultLogRotateAge,
+	}
+
+	if err := l.openShard(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// shardPath 返回当前序号对应的分片文件路径
+func (l *RotatingLogger) shardPath(seq int) string {
+	return filepath.Join(l.LogDir, fmt.Sprintf("download_log_%s.%d.jsonl", l.Timestamp, seq))
+}
+
+// openShard 打开当前序号对应的分片文件（调用方需持有 l.mu）
+func (l *RotatingLogger) openShard() error {
+	file, err := os.Create(l.shardPath(l.seq))
+	if err != nil {
+		return fmt.Errorf("无法创建日志分片: %v", err)
+	}
+	l.file = file
+	l.writer = bufio.NewWriter(file)
+	l.size = 0
+	l.openedAt = time.Now()
+	return nil
+}
+
+// rotate 关闭当前分片、递增序号并打开新分片（调用方需持有 l.mu）。
+// 先 flush+close 再进入下一个序号，避免 Windows 下重命名/打开同一文件句柄冲突。
+func (l *RotatingLogger) rotate() error {
+	if err := l.writer.Flush(); err != nil {
+		return err
+	}
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.seq++
+	return l.openShard()
+}
+
+// LogResult 追加写入一条终态的下载结果，并在超出大小或时间阈值时自动滚动
+func (l *RotatingLogger) LogResult(result DownloadResult, mirror string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := rotatingLogEntry{
+		DOI:       result.DOI,
+		Status:    result.Status,
+		Mirror:    mirror,
+		ElapsedMs: result.Duration.Milliseconds(),
+		Error:     result.Error,
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("无法序列化日志记录: %v", err)
+	}
+	line = append(line, '\n')
+
+	if _, err := l.writer.Write(line); err != nil {
+		return fmt.Errorf("写入日志分片失败: %v", err)
+	}
+	if err := l.writer.Flush(); err != nil {
+		return fmt.Errorf("刷新日志分片失败: %v", err)
+	}
+	l.size += int64(len(line))
+
+	if l.size >= l.RotateSize || time.Since(l.openedAt) >= l.RotateAge {
+		return l.rotate()
+	}
+
+	return nil
+}
+
+// Close 刷新并关闭当前分片文件
+func (l *RotatingLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.writer.Flush(); err != nil {
+		return err
+	}
+	return l.file.Close()
+}
+
+// ShardPaths 列出 logDir 下属于某次运行（由 timestamp 标识）的所有 JSONL 分片，按序号排序
+func ShardPaths(logDir, timestamp string) ([]string, error) {
+	entries, err := os.ReadDir(logDir)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := fmt.Sprintf("download_log_%s.", timestamp)
+	type shard struct {
+		seq  int
+		path string
+	}
+	shards := make([]shard, 0)
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		seqStr := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".jsonl")
+		seq, err := strconv.Atoi(seqStr)
+		if err != nil {
+			continue
+		}
+		shards = append(shards, shard{seq: seq, path: filepath.Join(logDir, name)})
+	}
+
+	sort.Slice(shards, func(i, j int) bool { return shards[i].seq < shards[j].seq })
+
+	paths := make([]string, len(shards))
+	for i, s := range shards {
+		paths[i] = s.path
+	}
+	return paths, nil
+}
+
+// ReconstructStatsFromShards 读取某次运行的所有 JSONL 分片，重建出 DownloadStats 中的
+// Total/Success/Skip/Failed、AllTimes/SuccessTime 与 Errors。即使进程在下载过程中被杀死，
+// 只要分片文件还在，这些字段就能恢复。TotalSize、TotalTime、ResolverCounts、Mirrors 不会被重建；
+// 分片只记录经过 worker 的结果，入队前就被 Manifest/History 跳过的 DOI 不计入 Total 与 Skip。
+func ReconstructStatsFromShards(logDir, timestamp string) (*DownloadStats, error) {
+	paths, err := ShardPaths(logDir, timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &DownloadStats{
+		Errors:      make([]DownloadError, 0),
+		AllTimes:    make([]time.Duration, 0),
+		SuccessTime: make([]time.Duration, 0),
+	}
+
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("无法打开日志分片 %s: %v", path, err)
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			var entry rotatingLogEntry
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				continue
+			}
+
+			elapsed := time.Duration(entry.ElapsedMs) * time.Millisecond
+			stats.Total++
+			stats.AllTimes = append(stats.AllTimes, elapsed)
+
+			switch entry.Status {
+			case "success":
+				stats.Success++
+				stats.SuccessTime = append(stats.SuccessTime, elapsed)
+			case "skip":
+				stats.Skip++
+			case "failed":
+				stats.Failed++
+				stats.Errors = append(stats.Errors, DownloadError{
+					DOI: entry.DOI, Error: entry.Error, Time: time.Now(),
+				})
+			}
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("读取日志分片 %s 失败: %v", path, err)
+		}
+	}
+
+	return stats, nil
+}
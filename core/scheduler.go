@@ -0,0 +1,88 @@
+// core/scheduler.go
+
+package core
+
+import "sync"
+
+// PriorityJob 是调度器中排队的一个下载任务
+type PriorityJob struct {
+	DOI      string
+	Priority int // 数值越大优先级越高
+}
+
+// PriorityScheduler 按优先级调度 DOI：内部用 map[int][]PriorityJob 把任务分桶，
+// Pop 总是从当前非空的最高优先级桶里取任务，参考 Pholcus 的 Matrix 调度器实现。
+// 相比单个 FIFO channel，这让"重试的 DOI""补采的 DOI"可以插队到常规任务之前或之后处理，
+// 而不需要为每种优先级单独起一条流水线。
+type PriorityScheduler struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	buckets map[int][]PriorityJob
+	closed  bool
+}
+
+// NewPriorityScheduler 创建一个空的优先级调度器
+func NewPriorityScheduler() *PriorityScheduler {
+	s := &PriorityScheduler{
+		buckets: make(map[int][]PriorityJob),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Push 把一个任务加入调度器，唤醒一个可能在等待任务的 Pop 调用者
+func (s *PriorityScheduler) Push(job PriorityJob) {
+	s.mu.Lock()
+	s.buckets[job.Priority] = append(s.buckets[job.Priority], job)
+	s.mu.Unlock()
+	s.cond.Signal()
+}
+
+// Pop 取出当前优先级最高的任务；调度器为空且已关闭时返回 ok=false。
+// 为空但未关闭时阻塞等待，直到有新任务或调度器被关闭。
+func (s *PriorityScheduler) Pop() (PriorityJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		if job, ok := s.popHighestLocked(); ok {
+			return job, true
+		}
+		if s.closed {
+			return PriorityJob{}, false
+		}
+		s.cond.Wait()
+	}
+}
+
+// popHighestLocked 从非空的最高优先级桶里弹出一个任务（调用方需持有 s.mu）
+func (s *PriorityScheduler) popHighestLocked() (PriorityJob, bool) {
+	best := 0
+	found := false
+	for priority, bucket := range s.buckets {
+		if len(bucket) == 0 {
+			continue
+		}
+		if !found || priority > best {
+			best = priority
+			found = true
+		}
+	}
+	if !found {
+		return PriorityJob{}, false
+	}
+
+	bucket := s.buckets[best]
+	job := bucket[0]
+	s.buckets[best] = bucket[1:]
+	return job, true
+}
+
+// Close 把调度器标记为已关闭；所有阻塞中的 Pop 会在排空现有任务后返回 ok=false。
+// Push 不检查该标记，关闭后 Push 的任务仍会进入桶中。
+func (s *PriorityScheduler) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
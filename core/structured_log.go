@@ -0,0 +1,62 @@
+// core/structured_log.go
+
+package core
+
+import (
+	"fmt"
+	"log"
+)
+
+// LogFields 是附加到一条结构化日志上的上下文字段，例如 {"doi": doi, "mirror": baseURL}
+type LogFields map[string]interface{}
+
+// StructuredLogger 是一个可插拔的结构化日志接口，便于调用方接入 zap/log4go 等框架，
+// 取代下载路径中原先直接写 os.Stderr 或静默落盘调试文件（如 downloadSinglePDF 里
+// 保存 debug HTML）的零散写法：调用方可以把这些事件统一路由到自己的日志系统，
+// 并保留 doi 等结构化字段用于检索。
+type StructuredLogger interface {
+	Debug(msg string, fields LogFields)
+	Info(msg string, fields LogFields)
+	Warn(msg string, fields LogFields)
+	Error(msg string, fields LogFields)
+}
+
+// NoopStructuredLogger 丢弃所有日志，是未配置 StructuredLogger 时的等价行为
+type NoopStructuredLogger struct{}
+
+func (NoopStructuredLogger) Debug(string, LogFields) {}
+func (NoopStructuredLogger) Info(string, LogFields)  {}
+func (NoopStructuredLogger) Warn(string, LogFields)  {}
+func (NoopStructuredLogger) Error(string, LogFields) {}
+
+// StderrLogger 是开箱即用的默认实现，按 "[LEVEL] msg key=value ..." 的格式写到标准错误
+type StderrLogger struct{}
+
+func (StderrLogger) emit(level, msg string, fields LogFields) {
+	line := fmt.Sprintf("[%s] %s", level, msg)
+	for k, v := range fields {
+		line += fmt.Sprintf(" %s=%v", k, v)
+	}
+	log.Println(line)
+}
+
+func (l StderrLogger) Debug(msg string, fields LogFields) { l.emit("DEBUG", msg, fields) }
+func (l StderrLogger) Info(msg string, fields LogFields)  { l.emit("INFO", msg, fields) }
+func (l StderrLogger) Warn(msg string, fields LogFields)  { l.emit("WARN", msg, fields) }
+func (l StderrLogger) Error(msg string, fields LogFields) { l.emit("ERROR", msg, fields) }
+
+// logDebug/logWarn 是 nil 安全的调用入口：一个 nil 的 StructuredLogger 接口值本身没有
+// 具体类型可以分发方法调用，直接调用会 panic，所以在调用方统一经过这两个小助手。
+func logDebug(logger StructuredLogger, msg string, fields LogFields) {
+	if logger == nil {
+		return
+	}
+	logger.Debug(msg, fields)
+}
+
+func logWarn(logger StructuredLogger, msg string, fields LogFields) {
+	if logger == nil {
+		return
+	}
+	logger.Warn(msg, fields)
+}